@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	cfg "github.com/conductorone/baton-rootly/pkg/config"
 	"github.com/conductorone/baton-rootly/pkg/connector"
@@ -43,15 +44,63 @@ func main() {
 func getConnector(ctx context.Context, rc *cfg.Rootly) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
+	err := cfg.ValidateConfig(rc)
+	if err != nil {
+		l.Error("invalid connector config", zap.Error(err))
+		return nil, err
+	}
+
 	apiKey := rc.ApiKey
 
-	c, err := connector.New(ctx, apiKey)
+	opts := []connector.Option{
+		connector.WithBaseURL(rc.BaseUrl),
+		connector.WithPageSize(rc.PageSize),
+		connector.WithSkippedResourceTypes(rc.SkipResourceTypes...),
+		connector.WithSkippedEntitlements(rc.SkipEntitlements...),
+		connector.WithTeamNameFilter(rc.TeamIncludePattern, rc.TeamExcludePattern),
+		connector.WithScheduleNameFilter(rc.ScheduleIncludePattern, rc.ScheduleExcludePattern),
+	}
+	if rc.OnCallWindow != "" {
+		onCallWindow, err := time.ParseDuration(rc.OnCallWindow)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, connector.WithOnCallWindow(onCallWindow))
+	}
+	var retryMaxElapsed time.Duration
+	if rc.RetryMaxElapsed != "" {
+		retryMaxElapsed, err = time.ParseDuration(rc.RetryMaxElapsed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, connector.WithRetryPolicy(rc.RetryMaxAttempts, retryMaxElapsed))
+	opts = append(opts, connector.WithConcurrency(rc.Concurrency))
+	if rc.SyncUserContacts {
+		opts = append(opts, connector.WithUserContactsSync())
+	}
+	if rc.IncrementalSync {
+		var fullSyncInterval time.Duration
+		if rc.FullSyncInterval != "" {
+			fullSyncInterval, err = time.ParseDuration(rc.FullSyncInterval)
+			if err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, connector.WithIncrementalSync(fullSyncInterval))
+	}
+
+	c, err := connector.New(ctx, apiKey, opts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
 
-	server, err := connectorbuilder.NewConnector(ctx, c)
+	var builderOpts []connectorbuilder.Opt
+	if rc.Ticketing {
+		builderOpts = append(builderOpts, connectorbuilder.WithTicketingEnabled())
+	}
+	server, err := connectorbuilder.NewConnector(ctx, c, builderOpts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -0,0 +1,146 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: rootly/v1/annotation_warning.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Warning annotates a response that succeeded despite something the caller should know about, e.g. the grants of a
+// resource deleted during the sync that were skipped.
+type Warning struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The description of what happened.
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// The resource type ID of the resource the warning is about, if any.
+	ResourceTypeId string `protobuf:"bytes,2,opt,name=resource_type_id,json=resourceTypeId,proto3" json:"resource_type_id,omitempty"`
+	// The ID of the resource the warning is about, if any.
+	ResourceId    string `protobuf:"bytes,3,opt,name=resource_id,json=resourceId,proto3" json:"resource_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Warning) Reset() {
+	*x = Warning{}
+	mi := &file_rootly_v1_annotation_warning_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Warning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Warning) ProtoMessage() {}
+
+func (x *Warning) ProtoReflect() protoreflect.Message {
+	mi := &file_rootly_v1_annotation_warning_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Warning.ProtoReflect.Descriptor instead.
+func (*Warning) Descriptor() ([]byte, []int) {
+	return file_rootly_v1_annotation_warning_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Warning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Warning) GetResourceTypeId() string {
+	if x != nil {
+		return x.ResourceTypeId
+	}
+	return ""
+}
+
+func (x *Warning) GetResourceId() string {
+	if x != nil {
+		return x.ResourceId
+	}
+	return ""
+}
+
+var File_rootly_v1_annotation_warning_proto protoreflect.FileDescriptor
+
+const file_rootly_v1_annotation_warning_proto_rawDesc = "" +
+	"\n" +
+	"\"rootly/v1/annotation_warning.proto\x12\trootly.v1\"n\n" +
+	"\aWarning\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12(\n" +
+	"\x10resource_type_id\x18\x02 \x01(\tR\x0eresourceTypeId\x12\x1f\n" +
+	"\vresource_id\x18\x03 \x01(\tR\n" +
+	"resourceIdB3Z1github.com/conductorone/baton-rootly/pb/rootly/v1b\x06proto3"
+
+var (
+	file_rootly_v1_annotation_warning_proto_rawDescOnce sync.Once
+	file_rootly_v1_annotation_warning_proto_rawDescData []byte
+)
+
+func file_rootly_v1_annotation_warning_proto_rawDescGZIP() []byte {
+	file_rootly_v1_annotation_warning_proto_rawDescOnce.Do(func() {
+		file_rootly_v1_annotation_warning_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rootly_v1_annotation_warning_proto_rawDesc), len(file_rootly_v1_annotation_warning_proto_rawDesc)))
+	})
+	return file_rootly_v1_annotation_warning_proto_rawDescData
+}
+
+var file_rootly_v1_annotation_warning_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_rootly_v1_annotation_warning_proto_goTypes = []any{
+	(*Warning)(nil), // 0: rootly.v1.Warning
+}
+var file_rootly_v1_annotation_warning_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_rootly_v1_annotation_warning_proto_init() }
+func file_rootly_v1_annotation_warning_proto_init() {
+	if File_rootly_v1_annotation_warning_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rootly_v1_annotation_warning_proto_rawDesc), len(file_rootly_v1_annotation_warning_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_rootly_v1_annotation_warning_proto_goTypes,
+		DependencyIndexes: file_rootly_v1_annotation_warning_proto_depIdxs,
+		MessageInfos:      file_rootly_v1_annotation_warning_proto_msgTypes,
+	}.Build()
+	File_rootly_v1_annotation_warning_proto = out.File
+	file_rootly_v1_annotation_warning_proto_goTypes = nil
+	file_rootly_v1_annotation_warning_proto_depIdxs = nil
+}
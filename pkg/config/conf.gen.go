@@ -5,6 +5,22 @@ import "reflect"
 
 type Rootly struct {
 	ApiKey string `mapstructure:"api-key"`
+	BaseUrl string `mapstructure:"base-url"`
+	PageSize int `mapstructure:"page-size"`
+	OnCallWindow string `mapstructure:"on-call-window"`
+	IncrementalSync bool `mapstructure:"incremental-sync"`
+	FullSyncInterval string `mapstructure:"full-sync-interval"`
+	SkipResourceTypes []string `mapstructure:"skip-resource-types"`
+	SkipEntitlements []string `mapstructure:"skip-entitlements"`
+	TeamIncludePattern string `mapstructure:"team-include-pattern"`
+	TeamExcludePattern string `mapstructure:"team-exclude-pattern"`
+	ScheduleIncludePattern string `mapstructure:"schedule-include-pattern"`
+	ScheduleExcludePattern string `mapstructure:"schedule-exclude-pattern"`
+	RetryMaxAttempts int `mapstructure:"retry-max-attempts"`
+	RetryMaxElapsed string `mapstructure:"retry-max-elapsed"`
+	Concurrency int `mapstructure:"concurrency"`
+	SyncUserContacts bool `mapstructure:"sync-user-contacts"`
+	Ticketing bool `mapstructure:"ticketing"`
 }
 
 func (c* Rootly) findFieldByTag(tagValue string) (any, bool) {
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
 	"github.com/conductorone/baton-sdk/pkg/field"
 )
 
@@ -12,10 +17,111 @@ var (
 		field.WithRequired(true),
 		field.WithIsSecret(true),
 	)
+	BaseURLField = field.StringField(
+		"base-url",
+		field.WithDisplayName("Base URL"),
+		field.WithDescription("The base URL of the Rootly API, e.g. for a regional endpoint. Defaults to https://api.rootly.com"),
+	)
+	PageSizeField = field.IntField(
+		"page-size",
+		field.WithDisplayName("Page size"),
+		field.WithDescription("The number of resources to request per page from the Rootly API. Defaults to 200"),
+	)
+	OnCallWindowField = field.StringField(
+		"on-call-window",
+		field.WithDisplayName("On-call window"),
+		field.WithDescription("How far ahead of now to look for schedule shifts when syncing on-call members, e.g. 24h"),
+		field.WithDefaultValue("1h"),
+	)
+	IncrementalSyncField = field.BoolField(
+		"incremental-sync",
+		field.WithDisplayName("Incremental sync"),
+		field.WithDescription("Reuse schedule membership grants from the previous sync when neither the schedule nor its rotations have changed since then"),
+	)
+	FullSyncIntervalField = field.StringField(
+		"full-sync-interval",
+		field.WithDisplayName("Full sync interval"),
+		field.WithDescription("How long schedule membership grants may be reused by incremental syncs before they are fetched again, e.g. 24h"),
+		field.WithDefaultValue("24h"),
+	)
+	SkipResourceTypesField = field.StringSliceField(
+		"skip-resource-types",
+		field.WithDisplayName("Skip resource types"),
+		field.WithDescription("The resource types not to sync, among team, secret, and schedule"),
+	)
+	SkipEntitlementsField = field.StringSliceField(
+		"skip-entitlements",
+		field.WithDisplayName("Skip entitlements"),
+		field.WithDescription("The entitlements not to sync, formatted as <resource type>:<entitlement>, e.g. schedule:on-call"),
+	)
+	TeamIncludePatternField = field.StringField(
+		"team-include-pattern",
+		field.WithDisplayName("Team include pattern"),
+		field.WithDescription("Only sync the teams whose name matches this regular expression, e.g. ^prod-"),
+	)
+	TeamExcludePatternField = field.StringField(
+		"team-exclude-pattern",
+		field.WithDisplayName("Team exclude pattern"),
+		field.WithDescription("Do not sync the teams whose name matches this regular expression"),
+	)
+	ScheduleIncludePatternField = field.StringField(
+		"schedule-include-pattern",
+		field.WithDisplayName("Schedule include pattern"),
+		field.WithDescription("Only sync the schedules whose name matches this regular expression, e.g. ^prod-"),
+	)
+	ScheduleExcludePatternField = field.StringField(
+		"schedule-exclude-pattern",
+		field.WithDisplayName("Schedule exclude pattern"),
+		field.WithDescription("Do not sync the schedules whose name matches this regular expression"),
+	)
+	RetryMaxAttemptsField = field.IntField(
+		"retry-max-attempts",
+		field.WithDisplayName("Retry max attempts"),
+		field.WithDescription("How many times in total to attempt a request to the Rootly API failing with a transient error, e.g. a 502 or 429"),
+		field.WithDefaultValue(4),
+	)
+	RetryMaxElapsedField = field.StringField(
+		"retry-max-elapsed",
+		field.WithDisplayName("Retry max elapsed time"),
+		field.WithDescription("How long to keep retrying a request to the Rootly API failing with a transient error, e.g. 2m"),
+		field.WithDefaultValue("2m"),
+	)
+	ConcurrencyField = field.IntField(
+		"concurrency",
+		field.WithDisplayName("Concurrency"),
+		field.WithDescription("How many requests to send to the Rootly API at once when fetching schedule rotation members, team memberships, and user contact methods"),
+		field.WithDefaultValue(4),
+	)
+	SyncUserContactsField = field.BoolField(
+		"sync-user-contacts",
+		field.WithDisplayName("Sync user contacts"),
+		field.WithDescription("Fetch the verified contact methods and notification rules of each user into their profile, which takes three more requests per user"),
+	)
+	// TicketingField is the ticketing flag of the SDK, exported so that the connector only advertises ticketing when
+	// it's enabled.
+	TicketingField = field.TicketingField.ExportAs(field.ExportTargetGUI)
 
 	//go:generate go run ./gen
 	Config = field.NewConfiguration(
-		[]field.SchemaField{APIKeyField},
+		[]field.SchemaField{
+			APIKeyField,
+			BaseURLField,
+			PageSizeField,
+			OnCallWindowField,
+			IncrementalSyncField,
+			FullSyncIntervalField,
+			SkipResourceTypesField,
+			SkipEntitlementsField,
+			TeamIncludePatternField,
+			TeamExcludePatternField,
+			ScheduleIncludePatternField,
+			ScheduleExcludePatternField,
+			RetryMaxAttemptsField,
+			RetryMaxElapsedField,
+			ConcurrencyField,
+			SyncUserContactsField,
+			TicketingField,
+		},
 		field.WithConnectorDisplayName("Rootly"),
 		field.WithHelpUrl("/docs/baton/rootly"),
 		field.WithIconUrl("/static/app-icons/rootly.svg"),
@@ -27,3 +133,60 @@ var (
 	// marked as mutually exclusive from the username password pair.
 	FieldRelationships = []field.SchemaFieldRelationship{}
 )
+
+// ValidateConfig checks the config values that can't be validated by the field rules alone.
+func ValidateConfig(c *Rootly) error {
+	if c.BaseUrl != "" {
+		baseURL, err := url.Parse(c.BaseUrl)
+		if err != nil {
+			return fmt.Errorf("invalid base-url %q: %w", c.BaseUrl, err)
+		}
+		if baseURL.Scheme != "https" && baseURL.Scheme != "http" {
+			return fmt.Errorf("invalid base-url %q: scheme must be https or http", c.BaseUrl)
+		}
+		if baseURL.Host == "" {
+			return fmt.Errorf("invalid base-url %q: host is required", c.BaseUrl)
+		}
+		if baseURL.RawQuery != "" || baseURL.Fragment != "" {
+			return fmt.Errorf("invalid base-url %q: query and fragment are not allowed", c.BaseUrl)
+		}
+	}
+	if c.PageSize < 0 {
+		return fmt.Errorf("invalid page-size %d: must not be negative", c.PageSize)
+	}
+	if c.OnCallWindow != "" {
+		if d, err := time.ParseDuration(c.OnCallWindow); err != nil || d <= 0 {
+			return fmt.Errorf("invalid on-call-window %q: must be a positive duration, e.g. 24h", c.OnCallWindow)
+		}
+	}
+	if c.FullSyncInterval != "" {
+		if d, err := time.ParseDuration(c.FullSyncInterval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid full-sync-interval %q: must be a positive duration, e.g. 24h", c.FullSyncInterval)
+		}
+	}
+	if c.RetryMaxAttempts < 0 {
+		return fmt.Errorf("invalid retry-max-attempts %d: must not be negative", c.RetryMaxAttempts)
+	}
+	if c.RetryMaxElapsed != "" {
+		if d, err := time.ParseDuration(c.RetryMaxElapsed); err != nil || d <= 0 {
+			return fmt.Errorf("invalid retry-max-elapsed %q: must be a positive duration, e.g. 2m", c.RetryMaxElapsed)
+		}
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d: must not be negative", c.Concurrency)
+	}
+	for _, pattern := range []struct {
+		field field.SchemaField
+		value string
+	}{
+		{TeamIncludePatternField, c.TeamIncludePattern},
+		{TeamExcludePatternField, c.TeamExcludePattern},
+		{ScheduleIncludePatternField, c.ScheduleIncludePattern},
+		{ScheduleExcludePatternField, c.ScheduleExcludePattern},
+	} {
+		if _, err := regexp.Compile(pattern.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", pattern.field.FieldName, pattern.value, err)
+		}
+	}
+	return nil
+}
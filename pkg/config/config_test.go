@@ -43,3 +43,129 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConfigValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Rootly
+		wantErr string
+	}{
+		{
+			name: "valid config - defaults",
+			config: &Rootly{
+				ApiKey: "abc123",
+			},
+		},
+		{
+			name: "valid config - regional base url and page size",
+			config: &Rootly{
+				ApiKey:           "abc123",
+				BaseUrl:          "https://api.eu.rootly.com",
+				PageSize:         50,
+				OnCallWindow:     "24h",
+				FullSyncInterval: "168h",
+			},
+		},
+		{
+			name: "valid config - local mock",
+			config: &Rootly{
+				ApiKey:  "abc123",
+				BaseUrl: "http://localhost:8080",
+			},
+		},
+		{
+			name: "invalid config - base url without scheme",
+			config: &Rootly{
+				ApiKey:  "abc123",
+				BaseUrl: "api.rootly.com",
+			},
+			wantErr: "invalid base-url",
+		},
+		{
+			name: "invalid config - base url with query",
+			config: &Rootly{
+				ApiKey:  "abc123",
+				BaseUrl: "https://api.rootly.com?page[size]=1",
+			},
+			wantErr: "invalid base-url",
+		},
+		{
+			name: "invalid config - negative page size",
+			config: &Rootly{
+				ApiKey:   "abc123",
+				PageSize: -1,
+			},
+			wantErr: "invalid page-size",
+		},
+		{
+			name: "invalid config - on-call window is not a duration",
+			config: &Rootly{
+				ApiKey:       "abc123",
+				OnCallWindow: "1 day",
+			},
+			wantErr: "invalid on-call-window",
+		},
+		{
+			name: "invalid config - negative full sync interval",
+			config: &Rootly{
+				ApiKey:           "abc123",
+				FullSyncInterval: "-1h",
+			},
+			wantErr: "invalid full-sync-interval",
+		},
+		{
+			name: "valid config - name patterns",
+			config: &Rootly{
+				ApiKey:                 "abc123",
+				TeamIncludePattern:     "^prod-",
+				ScheduleExcludePattern: "(?i)test",
+			},
+		},
+		{
+			name: "invalid config - team include pattern is not a regular expression",
+			config: &Rootly{
+				ApiKey:             "abc123",
+				TeamIncludePattern: "prod-(",
+			},
+			wantErr: "invalid team-include-pattern",
+		},
+		{
+			name: "valid config - retry policy",
+			config: &Rootly{
+				ApiKey:           "abc123",
+				RetryMaxAttempts: 6,
+				RetryMaxElapsed:  "10m",
+			},
+		},
+		{
+			name: "invalid config - retry max elapsed is not a duration",
+			config: &Rootly{
+				ApiKey:          "abc123",
+				RetryMaxElapsed: "ten minutes",
+			},
+			wantErr: "invalid retry-max-elapsed",
+		},
+		{
+			name: "invalid config - negative concurrency",
+			config: &Rootly{
+				ApiKey:      "abc123",
+				Concurrency: -1,
+			},
+			wantErr: "invalid concurrency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				if err != nil {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
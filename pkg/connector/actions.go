@@ -0,0 +1,553 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	configv1 "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	createOverrideShiftAction = "create_override_shift"
+	pageOnCallAction          = "page_on_call"
+	transferOwnershipAction   = "transfer_ownership"
+)
+
+// pageTargetTypes maps the target types accepted by the page_on_call action to Rootly alert notification target types.
+var pageTargetTypes = map[string]string{
+	"schedule":          "Schedule",
+	"escalation_policy": "EscalationPolicy",
+	"team":              "Group",
+	"user":              "User",
+}
+
+// actionSchemas describes the custom actions of the connector and their arguments.
+var actionSchemas = []*v2.BatonActionSchema{
+	{
+		Name:        createOverrideShiftAction,
+		DisplayName: "Create on-call override shift",
+		Description: "Puts a member of a Rootly schedule on-call from the start time until the end time, " +
+			"overriding the schedule rotations.",
+		Arguments: []*configv1.Field{
+			stringActionField("schedule_id", "Schedule ID", "The ID of the schedule to override.", true),
+			stringActionField("user_id", "User ID", "The ID of the user to put on-call, a member of a rotation of the schedule.", true),
+			stringActionField("starts_at", "Start time", "The start of the override shift, e.g. 2025-04-10T09:00:00Z.", true),
+			stringActionField("ends_at", "End time", "The end of the override shift, e.g. 2025-04-10T17:00:00Z.", true),
+		},
+		ReturnTypes: []*configv1.Field{
+			stringActionField("override_shift_id", "Override shift ID", "The ID of the created override shift.", false),
+		},
+	},
+	{
+		Name:        pageOnCallAction,
+		DisplayName: "Page on-call",
+		Description: "Creates a Rootly alert paging whoever is on-call for a schedule, escalation policy, team, or user. " +
+			"The action completes once a paged user acknowledges the alert.",
+		Arguments: []*configv1.Field{
+			{
+				Name:        "target_type",
+				DisplayName: "Target type",
+				Description: "The kind of target to page.",
+				IsRequired:  true,
+				Field: &configv1.Field_StringField{StringField: &configv1.StringField{
+					Options: []*configv1.StringFieldOption{
+						{Name: "schedule", Value: "schedule", DisplayName: "Schedule"},
+						{Name: "escalation_policy", Value: "escalation_policy", DisplayName: "Escalation policy"},
+						{Name: "team", Value: "team", DisplayName: "Team"},
+						{Name: "user", Value: "user", DisplayName: "User"},
+					},
+				}},
+			},
+			stringActionField("target_id", "Target ID", "The ID of the schedule, escalation policy, team, or user to page.", true),
+			stringActionField("summary", "Summary", "The summary of the alert, shown to the paged users.", true),
+			stringActionField("description", "Description", "More details about the alert.", false),
+			stringActionField("urgency", "Urgency", "The name or ID of a Rootly alert urgency, e.g. High.", false),
+		},
+		ReturnTypes: []*configv1.Field{
+			stringActionField("alert_id", "Alert ID", "The ID of the created alert.", false),
+			stringActionField("alert_status", "Alert status", "The status of the alert, e.g. triggered or acknowledged.", false),
+		},
+	},
+	{
+		Name:        transferOwnershipAction,
+		DisplayName: "Transfer ownership",
+		Description: "Makes a user the owner of everything another user owns in Rootly, i.e. schedules, services, " +
+			"functionalities and escalation policies, e.g. when the other user leaves.",
+		Arguments: []*configv1.Field{
+			stringActionField("from_user_id", "From user ID", "The ID of the user whose ownership to transfer.", true),
+			stringActionField("to_user_id", "To user ID", "The ID of the user to transfer the ownership to.", true),
+			{
+				Name:        "dry_run",
+				DisplayName: "Dry run",
+				Description: "Only returns the objects whose ownership would be transferred, without changing them.",
+				Field:       &configv1.Field_BoolField{BoolField: &configv1.BoolField{}},
+			},
+		},
+		ReturnTypes: []*configv1.Field{
+			{
+				Name:        "changed_objects",
+				DisplayName: "Changed objects",
+				Description: "The objects whose ownership was, or would be for a dry run, transferred, e.g. schedule:<id>.",
+				Field:       &configv1.Field_StringSliceField{StringSliceField: &configv1.StringSliceField{}},
+			},
+			{
+				Name:        "dry_run",
+				DisplayName: "Dry run",
+				Description: "Whether the objects were left unchanged.",
+				Field:       &configv1.Field_BoolField{BoolField: &configv1.BoolField{}},
+			},
+		},
+	},
+}
+
+// ownershipChange is a change of the owners of a Rootly object, planned by the transfer_ownership action.
+type ownershipChange struct {
+	// object is the type and ID of the object, e.g. schedule:<id>.
+	object string
+	name   string
+	apply  func(ctx context.Context) error
+}
+
+// actionManager runs the custom actions of the connector against the Rootly API.
+type actionManager struct {
+	client *client.Client
+	now    func() time.Time
+}
+
+func (m *actionManager) ListActionSchemas(_ context.Context) ([]*v2.BatonActionSchema, annotations.Annotations, error) {
+	return actionSchemas, nil, nil
+}
+
+func (m *actionManager) GetActionSchema(_ context.Context, name string) (*v2.BatonActionSchema, annotations.Annotations, error) {
+	for _, schema := range actionSchemas {
+		if schema.Name == name {
+			return schema, nil, nil
+		}
+	}
+	return nil, nil, status.Errorf(codes.NotFound, "unknown action %q", name)
+}
+
+// InvokeAction runs an action. The returned ID is "<action name>:<ID>", with the ID of the Rootly object the action
+// created, or the IDs of the users whose ownership it transferred, which GetActionStatus takes to report the status of
+// the action later on.
+func (m *actionManager) InvokeAction(
+	ctx context.Context,
+	name string,
+	args *structpb.Struct,
+) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	switch name {
+	case createOverrideShiftAction:
+		return m.createOverrideShift(ctx, args)
+	case pageOnCallAction:
+		return m.pageOnCall(ctx, args)
+	case transferOwnershipAction:
+		return m.transferOwnership(ctx, args)
+	default:
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, nil, status.Errorf(codes.NotFound, "unknown action %q", name)
+	}
+}
+
+// GetActionStatus returns the status of an action invoked earlier: override shifts and ownership transfers are
+// complete by the time InvokeAction returns, while pages are running until a paged user acknowledges, or someone resolves, the alert.
+func (m *actionManager) GetActionStatus(
+	ctx context.Context,
+	id string,
+) (v2.BatonActionStatus, string, *structpb.Struct, annotations.Annotations, error) {
+	name, objectID, ok := strings.Cut(id, ":")
+	if !ok || objectID == "" {
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, "", nil, nil, status.Errorf(codes.InvalidArgument, "invalid action ID %q", id)
+	}
+	switch name {
+	case createOverrideShiftAction:
+		result, err := structpb.NewStruct(map[string]interface{}{
+			"override_shift_id": objectID,
+		})
+		if err != nil {
+			return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, name, nil, nil, err
+		}
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, name, result, nil, nil
+	case transferOwnershipAction:
+		// the objects changed were returned by InvokeAction, and can't be told apart from those changed since
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, name, nil, nil, nil
+	case pageOnCallAction:
+		alert, err := m.client.GetAlert(ctx, objectID)
+		if err != nil {
+			return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, name, nil, withRateLimit(nil, m.client), err
+		}
+		result, err := alertResult(alert)
+		if err != nil {
+			return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, name, nil, nil, err
+		}
+		return alertActionStatus(alert), name, result, withRateLimit(nil, m.client), nil
+	default:
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_UNKNOWN, "", nil, nil, status.Errorf(codes.NotFound, "unknown action %q", name)
+	}
+}
+
+// createOverrideShift puts a member of a schedule rotation on-call for the schedule during the given times.
+func (m *actionManager) createOverrideShift(
+	ctx context.Context,
+	args *structpb.Struct,
+) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	failed := func(err error) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, withRateLimit(nil, m.client), err
+	}
+
+	scheduleID, err := stringActionArg(args, "schedule_id")
+	if err != nil {
+		return failed(err)
+	}
+	userIDArg, err := stringActionArg(args, "user_id")
+	if err != nil {
+		return failed(err)
+	}
+	userID, err := strconv.Atoi(userIDArg)
+	if err != nil {
+		return failed(status.Errorf(codes.InvalidArgument, "user_id %q isn't a Rootly user ID", userIDArg))
+	}
+	startsAt, err := timeActionArg(args, "starts_at")
+	if err != nil {
+		return failed(err)
+	}
+	endsAt, err := timeActionArg(args, "ends_at")
+	if err != nil {
+		return failed(err)
+	}
+	if !endsAt.After(startsAt) {
+		return failed(status.Errorf(codes.InvalidArgument, "ends_at must be after starts_at"))
+	}
+	if !endsAt.After(m.now()) {
+		return failed(status.Errorf(codes.InvalidArgument, "ends_at must be in the future"))
+	}
+
+	// the rotations may have changed since the sync, so their members aren't read from the GET cache
+	memberIDs, err := m.client.Uncached().ListAllScheduleMemberIDs(ctx, scheduleID)
+	if err != nil {
+		return failed(err)
+	}
+	if !slices.Contains(memberIDs, userID) {
+		return failed(status.Errorf(
+			codes.FailedPrecondition,
+			"user %d isn't a member of a rotation of schedule %s",
+			userID,
+			scheduleID,
+		))
+	}
+
+	overrideShift, err := m.client.CreateOverrideShift(ctx, scheduleID, userID, startsAt, endsAt)
+	if err != nil {
+		return failed(err)
+	}
+	ctxzap.Extract(ctx).Info(
+		"Created on-call override shift",
+		zap.String("schedule_id", scheduleID),
+		zap.Int("user_id", userID),
+		zap.String("override_shift_id", overrideShift.ID),
+	)
+
+	result, err := structpb.NewStruct(map[string]interface{}{
+		"override_shift_id": overrideShift.ID,
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return fmt.Sprintf("%s:%s", createOverrideShiftAction, overrideShift.ID),
+		v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE,
+		result,
+		withRateLimit(nil, m.client),
+		nil
+}
+
+// pageOnCall creates an alert paging the on-call users of a schedule, escalation policy, team, or user.
+func (m *actionManager) pageOnCall(
+	ctx context.Context,
+	args *structpb.Struct,
+) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	failed := func(err error) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, withRateLimit(nil, m.client), err
+	}
+
+	targetType, err := stringActionArg(args, "target_type")
+	if err != nil {
+		return failed(err)
+	}
+	notificationTargetType, ok := pageTargetTypes[targetType]
+	if !ok {
+		return failed(status.Errorf(codes.InvalidArgument, "unknown target_type %q, expected schedule, escalation_policy, team or user", targetType))
+	}
+	targetID, err := stringActionArg(args, "target_id")
+	if err != nil {
+		return failed(err)
+	}
+	summary, err := stringActionArg(args, "summary")
+	if err != nil {
+		return failed(err)
+	}
+	attributes := client.AlertRequestAttributes{
+		Summary:                summary,
+		Description:            args.GetFields()["description"].GetStringValue(),
+		Source:                 "api",
+		NotificationTargetType: notificationTargetType,
+		NotificationTargetID:   targetID,
+	}
+	if urgency := args.GetFields()["urgency"].GetStringValue(); urgency != "" {
+		attributes.AlertUrgencyID, err = m.alertUrgencyID(ctx, urgency)
+		if err != nil {
+			return failed(err)
+		}
+	}
+
+	alert, err := m.client.CreateAlert(ctx, attributes)
+	if err != nil {
+		return failed(err)
+	}
+	ctxzap.Extract(ctx).Info(
+		"Paged on-call",
+		zap.String("target_type", targetType),
+		zap.String("target_id", targetID),
+		zap.String("alert_id", alert.ID),
+	)
+
+	result, err := alertResult(alert)
+	if err != nil {
+		return failed(err)
+	}
+	return fmt.Sprintf("%s:%s", pageOnCallAction, alert.ID), alertActionStatus(alert), result, withRateLimit(nil, m.client), nil
+}
+
+// transferOwnership makes a user the owner of the schedules, services, functionalities and escalation policies owned
+// by another user. For a dry run, it only returns the objects it would change.
+func (m *actionManager) transferOwnership(
+	ctx context.Context,
+	args *structpb.Struct,
+) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+	failed := func(err error) (string, v2.BatonActionStatus, *structpb.Struct, annotations.Annotations, error) {
+		return "", v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, nil, withRateLimit(nil, m.client), err
+	}
+
+	var userIDs [2]int
+	for i, name := range []string{"from_user_id", "to_user_id"} {
+		userIDArg, err := stringActionArg(args, name)
+		if err != nil {
+			return failed(err)
+		}
+		userIDs[i], err = strconv.Atoi(userIDArg)
+		if err != nil {
+			return failed(status.Errorf(codes.InvalidArgument, "%s %q isn't a Rootly user ID", name, userIDArg))
+		}
+	}
+	fromUserID, toUserID := userIDs[0], userIDs[1]
+	if fromUserID == toUserID {
+		return failed(status.Errorf(codes.InvalidArgument, "from_user_id and to_user_id must be different users"))
+	}
+	dryRun := args.GetFields()["dry_run"].GetBoolValue()
+
+	// the user transferring from may be gone already, but the one transferring to must exist
+	_, err := m.client.Uncached().GetUser(ctx, strconv.Itoa(toUserID))
+	if client.IsNotFound(err) {
+		return failed(status.Errorf(codes.InvalidArgument, "to_user_id %d isn't a Rootly user", toUserID))
+	}
+	if err != nil {
+		return failed(err)
+	}
+
+	changes, err := m.planOwnershipTransfer(ctx, fromUserID, toUserID)
+	if err != nil {
+		return failed(err)
+	}
+	logger := ctxzap.Extract(ctx)
+	changedObjects := make([]interface{}, 0, len(changes))
+	for _, change := range changes {
+		if !dryRun {
+			err := change.apply(ctx)
+			if err != nil {
+				return failed(fmt.Errorf(
+					"transferred the ownership of %d of %d objects, failed at %s: %w",
+					len(changedObjects),
+					len(changes),
+					change.object,
+					err,
+				))
+			}
+		}
+		logger.Info(
+			"Transferred ownership",
+			zap.String("object", change.object),
+			zap.String("name", change.name),
+			zap.Int("from_user_id", fromUserID),
+			zap.Int("to_user_id", toUserID),
+			zap.Bool("dry_run", dryRun),
+		)
+		changedObjects = append(changedObjects, change.object)
+	}
+
+	result, err := structpb.NewStruct(map[string]interface{}{
+		"changed_objects": changedObjects,
+		"dry_run":         dryRun,
+	})
+	if err != nil {
+		return failed(err)
+	}
+	return fmt.Sprintf("%s:%d:%d", transferOwnershipAction, fromUserID, toUserID),
+		v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE,
+		result,
+		withRateLimit(nil, m.client),
+		nil
+}
+
+// planOwnershipTransfer returns the changes making a user the owner of the objects owned by another user. The owners
+// are read bypassing the GET cache, since the changes replace the owners of each object as read.
+func (m *actionManager) planOwnershipTransfer(ctx context.Context, fromUserID int, toUserID int) ([]ownershipChange, error) {
+	var changes []ownershipChange
+	uncached := m.client.Uncached()
+
+	schedules, err := uncached.ListAllSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		if schedule.Attributes.OwnerUserID == nil || *schedule.Attributes.OwnerUserID != fromUserID {
+			continue
+		}
+		changes = append(changes, ownershipChange{
+			object: fmt.Sprintf("schedule:%s", schedule.ID),
+			name:   schedule.Attributes.Name,
+			apply: func(ctx context.Context) error {
+				_, err := m.client.UpdateScheduleOwner(ctx, schedule.ID, toUserID)
+				return err
+			},
+		})
+	}
+
+	for _, owned := range []struct {
+		objectType string
+		listAll    func(ctx context.Context) ([]client.OwnedObject, error)
+		update     func(ctx context.Context, id string, ownerUserIDs []int) (*client.OwnedObject, error)
+	}{
+		{"service", uncached.ListAllServices, m.client.UpdateServiceOwners},
+		{"functionality", uncached.ListAllFunctionalities, m.client.UpdateFunctionalityOwners},
+		{"escalation_policy", uncached.ListAllEscalationPolicies, m.client.UpdateEscalationPolicyOwners},
+	} {
+		objects, err := owned.listAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			if !slices.Contains(object.Attributes.OwnersUserIDs, fromUserID) {
+				continue
+			}
+			ownerUserIDs := replaceOwner(object.Attributes.OwnersUserIDs, fromUserID, toUserID)
+			changes = append(changes, ownershipChange{
+				object: fmt.Sprintf("%s:%s", owned.objectType, object.ID),
+				name:   object.Attributes.Name,
+				apply: func(ctx context.Context) error {
+					_, err := owned.update(ctx, object.ID, ownerUserIDs)
+					return err
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// replaceOwner returns the owner user IDs with the user transferring from replaced by the user transferring to, in
+// place, unless the latter is an owner already.
+func replaceOwner(ownerUserIDs []int, fromUserID int, toUserID int) []int {
+	replaced := make([]int, 0, len(ownerUserIDs))
+	for _, userID := range ownerUserIDs {
+		if userID == fromUserID {
+			userID = toUserID
+		}
+		if !slices.Contains(replaced, userID) {
+			replaced = append(replaced, userID)
+		}
+	}
+	return replaced
+}
+
+// alertUrgencyID returns the ID of the alert urgency with the given name, case-insensitively, or ID.
+func (m *actionManager) alertUrgencyID(ctx context.Context, urgency string) (string, error) {
+	urgencies, err := m.client.ListAllAlertUrgencies(ctx)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, alertUrgency := range urgencies {
+		if alertUrgency.ID == urgency || strings.EqualFold(alertUrgency.Attributes.Name, urgency) {
+			return alertUrgency.ID, nil
+		}
+		names = append(names, alertUrgency.Attributes.Name)
+	}
+	return "", status.Errorf(codes.InvalidArgument, "unknown urgency %q, expected one of %s", urgency, strings.Join(names, ", "))
+}
+
+// alertActionStatus returns the status of the page_on_call action for the current status of its alert.
+func alertActionStatus(alert *client.Alert) v2.BatonActionStatus {
+	switch alert.Attributes.Status {
+	case "acknowledged", "resolved":
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE
+	default:
+		return v2.BatonActionStatus_BATON_ACTION_STATUS_RUNNING
+	}
+}
+
+// alertResult returns the result of the page_on_call action for an alert.
+func alertResult(alert *client.Alert) (*structpb.Struct, error) {
+	return structpb.NewStruct(map[string]interface{}{
+		"alert_id":     alert.ID,
+		"alert_status": alert.Attributes.Status,
+	})
+}
+
+// stringActionField returns a string argument, or return value, of an action schema.
+func stringActionField(name string, displayName string, description string, required bool) *configv1.Field {
+	return &configv1.Field{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		IsRequired:  required,
+		Field:       &configv1.Field_StringField{StringField: &configv1.StringField{}},
+	}
+}
+
+// stringActionArg returns the value of a required string argument of an action.
+func stringActionArg(args *structpb.Struct, name string) (string, error) {
+	value, ok := args.GetFields()[name]
+	if !ok || value.GetStringValue() == "" {
+		return "", status.Errorf(codes.InvalidArgument, "%s is required", name)
+	}
+	return value.GetStringValue(), nil
+}
+
+// timeActionArg returns the value of a required RFC 3339 time argument of an action.
+func timeActionArg(args *structpb.Struct, name string) (time.Time, error) {
+	value, err := stringActionArg(args, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "%s must be an RFC 3339 time, e.g. 2025-04-10T09:00:00Z", name)
+	}
+	return t, nil
+}
+
+func newActionManager(client *client.Client) *actionManager {
+	return &actionManager{
+		client: client,
+		now:    time.Now,
+	}
+}
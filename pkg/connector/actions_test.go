@@ -0,0 +1,342 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_actionManager_createOverrideShift(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"GET /v1/schedules/test-schedule-guid/schedule_rotations":                       scheduleRotationsWithUsersResult,
+		"GET /v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users": weekendRotationUsersResult,
+	})
+	fake.handle("POST /v1/schedules/test-schedule-guid/override_shifts", func(writer http.ResponseWriter, request *http.Request) {
+		var req client.OverrideShiftRequest
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&req))
+		writeJSON(t, writer, http.StatusCreated, client.OverrideShiftResponse{Data: client.OverrideShift{
+			ID:   "test-override-shift-guid",
+			Type: "override_shifts",
+			Attributes: client.OverrideShiftAttributes{
+				ScheduleID: "test-schedule-guid",
+				UserID:     req.Data.Attributes.UserID,
+				StartsAt:   req.Data.Attributes.StartsAt,
+				EndsAt:     req.Data.Attributes.EndsAt,
+			},
+		}})
+	})
+	created := func() []client.OverrideShiftRequest {
+		return decodeBodies[client.OverrideShiftRequest](t, fake, http.MethodPost, "/v1/schedules/test-schedule-guid/override_shifts")
+	}
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	manager := newActionManager(rootlyClient)
+	manager.now = func() time.Time { return time.Date(2025, 4, 10, 8, 0, 0, 0, time.UTC) }
+
+	args := func(userID string, startsAt string, endsAt string) *structpb.Struct {
+		st, err := structpb.NewStruct(map[string]interface{}{
+			"schedule_id": "test-schedule-guid",
+			"user_id":     userID,
+			"starts_at":   startsAt,
+			"ends_at":     endsAt,
+		})
+		require.NoError(t, err)
+		return st
+	}
+
+	t.Run("member of a rotation", func(t *testing.T) {
+		// the user is a member of the weekend rotation, whose included users are truncated
+		id, actionStatus, result, _, err := manager.InvokeAction(ctx, createOverrideShiftAction,
+			args("98001", "2025-04-10T09:00:00Z", "2025-04-10T17:00:00+02:00"))
+		require.NoError(t, err)
+		require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+		require.Equal(t, "create_override_shift:test-override-shift-guid", id)
+		require.Equal(t, "test-override-shift-guid", result.Fields["override_shift_id"].GetStringValue())
+
+		require.Len(t, created(), 1)
+		require.Equal(t, "override_shifts", created()[0].Data.Type)
+		require.Equal(t, client.OverrideShiftRequestAttributes{
+			UserID:   98001,
+			StartsAt: "2025-04-10T09:00:00Z",
+			EndsAt:   "2025-04-10T15:00:00Z",
+		}, created()[0].Data.Attributes)
+	})
+
+	for _, tc := range []struct {
+		name string
+		args *structpb.Struct
+		code codes.Code
+	}{
+		{"not a member", args("12345", "2025-04-10T09:00:00Z", "2025-04-10T17:00:00Z"), codes.FailedPrecondition},
+		{"invalid user ID", args("jane", "2025-04-10T09:00:00Z", "2025-04-10T17:00:00Z"), codes.InvalidArgument},
+		{"missing start", args("96913", "", "2025-04-10T17:00:00Z"), codes.InvalidArgument},
+		{"invalid end", args("96913", "2025-04-10T09:00:00Z", "tomorrow"), codes.InvalidArgument},
+		{"end before start", args("96913", "2025-04-10T17:00:00Z", "2025-04-10T09:00:00Z"), codes.InvalidArgument},
+		{"past", args("96913", "2025-04-09T09:00:00Z", "2025-04-09T17:00:00Z"), codes.InvalidArgument},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, actionStatus, _, _, err := manager.InvokeAction(ctx, createOverrideShiftAction, tc.args)
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, actionStatus)
+		})
+	}
+	require.Len(t, created(), 1)
+	// the members are read again for each override checked, rather than from the GET cache
+	require.Equal(t, 2, fake.requestCount("/v1/schedules/test-schedule-guid/schedule_rotations"))
+}
+
+func Test_actionManager_schemas(t *testing.T) {
+	ctx := context.Background()
+	manager := newActionManager(nil)
+
+	schemas, _, err := manager.ListActionSchemas(ctx)
+	require.NoError(t, err)
+	for _, schema := range schemas {
+		got, _, err := manager.GetActionSchema(ctx, schema.Name)
+		require.NoError(t, err)
+		require.Equal(t, schema, got)
+	}
+
+	schema, _, err := manager.GetActionSchema(ctx, createOverrideShiftAction)
+	require.NoError(t, err)
+	var arguments []string
+	for _, argument := range schema.Arguments {
+		require.True(t, argument.IsRequired)
+		arguments = append(arguments, argument.Name)
+	}
+	require.Equal(t, []string{"schedule_id", "user_id", "starts_at", "ends_at"}, arguments)
+
+	_, _, err = manager.GetActionSchema(ctx, "unknown")
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_actionManager_pageOnCall(t *testing.T) {
+	var mu sync.Mutex
+	alertStatus := "triggered"
+	alert := func() client.AlertResponse {
+		mu.Lock()
+		defer mu.Unlock()
+		return client.AlertResponse{Data: client.Alert{
+			ID:         "test-alert-guid",
+			Type:       "alerts",
+			Attributes: client.AlertAttributes{Summary: "Database is down", Status: alertStatus},
+		}}
+	}
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"GET /v1/alert_urgencies": `{
+			"data": [
+				{"id": "test-high-urgency-guid", "type": "alert_urgencies", "attributes": {"name": "High"}},
+				{"id": "test-low-urgency-guid", "type": "alert_urgencies", "attributes": {"name": "Low"}}
+			],
+			"links": {"next": null}
+		}`,
+	})
+	fake.handle("POST /v1/alerts", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, writer, http.StatusCreated, alert())
+	})
+	fake.handle("GET /v1/alerts/test-alert-guid", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, writer, http.StatusOK, alert())
+	})
+	created := func() []client.AlertRequest {
+		return decodeBodies[client.AlertRequest](t, fake, http.MethodPost, "/v1/alerts")
+	}
+	setAlertStatus := func(status string) {
+		mu.Lock()
+		defer mu.Unlock()
+		alertStatus = status
+	}
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	manager := newActionManager(rootlyClient)
+
+	args := func(targetType string, urgency string) *structpb.Struct {
+		st, err := structpb.NewStruct(map[string]interface{}{
+			"target_type": targetType,
+			"target_id":   "test-escalation-policy-guid",
+			"summary":     "Database is down",
+			"urgency":     urgency,
+		})
+		require.NoError(t, err)
+		return st
+	}
+
+	id, actionStatus, result, _, err := manager.InvokeAction(ctx, pageOnCallAction, args("escalation_policy", "high"))
+	require.NoError(t, err)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_RUNNING, actionStatus)
+	require.Equal(t, "page_on_call:test-alert-guid", id)
+	require.Equal(t, "test-alert-guid", result.Fields["alert_id"].GetStringValue())
+	require.Equal(t, "triggered", result.Fields["alert_status"].GetStringValue())
+
+	require.Len(t, created(), 1)
+	require.Equal(t, "alerts", created()[0].Data.Type)
+	require.Equal(t, client.AlertRequestAttributes{
+		Summary:                "Database is down",
+		Source:                 "api",
+		NotificationTargetType: "EscalationPolicy",
+		NotificationTargetID:   "test-escalation-policy-guid",
+		AlertUrgencyID:         "test-high-urgency-guid",
+	}, created()[0].Data.Attributes)
+
+	actionStatus, name, result, _, err := manager.GetActionStatus(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, pageOnCallAction, name)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_RUNNING, actionStatus)
+	require.Equal(t, "triggered", result.Fields["alert_status"].GetStringValue())
+
+	// the status is polled from Rootly rather than the cache of the HTTP client
+	setAlertStatus("acknowledged")
+	actionStatus, _, result, _, err = manager.GetActionStatus(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+	require.Equal(t, "acknowledged", result.Fields["alert_status"].GetStringValue())
+
+	for _, tc := range []struct {
+		name string
+		args *structpb.Struct
+	}{
+		{"unknown target type", args("service", "")},
+		{"unknown urgency", args("schedule", "critical")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, actionStatus, _, _, err := manager.InvokeAction(ctx, pageOnCallAction, tc.args)
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+			require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, actionStatus)
+		})
+	}
+	require.Len(t, created(), 1)
+}
+
+func Test_actionManager_GetActionStatus(t *testing.T) {
+	ctx := context.Background()
+	manager := newActionManager(nil)
+
+	actionStatus, name, result, _, err := manager.GetActionStatus(ctx, "create_override_shift:test-override-shift-guid")
+	require.NoError(t, err)
+	require.Equal(t, createOverrideShiftAction, name)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+	require.Equal(t, "test-override-shift-guid", result.Fields["override_shift_id"].GetStringValue())
+
+	actionStatus, name, _, _, err = manager.GetActionStatus(ctx, "transfer_ownership:100:300")
+	require.NoError(t, err)
+	require.Equal(t, transferOwnershipAction, name)
+	require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+
+	_, _, _, _, err = manager.GetActionStatus(ctx, "unknown:test-guid")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, _, _, _, err = manager.GetActionStatus(ctx, "test-guid")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_actionManager_transferOwnership(t *testing.T) {
+	// the objects of user 100 are being transferred
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"GET /v1/users/300": `{"data": {"id": "300", "type": "users", "attributes": {"name": "Jane"}}}`,
+		"GET /v1/schedules": `{
+			"data": [
+				{"id": "test-owned-schedule-guid", "type": "schedules", "attributes": {"name": "Primary", "owner_user_id": 100}},
+				{"id": "test-other-schedule-guid", "type": "schedules", "attributes": {"name": "Secondary", "owner_user_id": 200}},
+				{"id": "test-unowned-schedule-guid", "type": "schedules", "attributes": {"name": "Tertiary", "owner_user_id": null}}
+			],
+			"links": {"next": null}
+		}`,
+		"GET /v1/services": `{
+			"data": [{"id": "test-service-guid", "type": "services", "attributes": {"name": "API", "owners_user_ids": [100, 200]}}],
+			"links": {"next": null}
+		}`,
+		"GET /v1/functionalities": `{
+			"data": [{"id": "test-functionality-guid", "type": "functionalities", "attributes": {"name": "Login", "owners_user_ids": [300, 100]}}],
+			"links": {"next": null}
+		}`,
+		"GET /v1/escalation_policies": `{
+			"data": [{"id": "test-escalation-policy-guid", "type": "escalation_policies", "attributes": {"name": "Default", "owners_user_ids": [200]}}],
+			"links": {"next": null}
+		}`,
+		"PUT /": `{"data": {"id": "updated", "type": "updated", "attributes": {}}}`,
+	})
+	updated := func() map[string]string {
+		updated := make(map[string]string)
+		for path, bodies := range fake.bodies(http.MethodPut) {
+			updated[path] = bodies[len(bodies)-1]
+		}
+		return updated
+	}
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	manager := newActionManager(rootlyClient)
+
+	args := func(fromUserID string, toUserID string, dryRun bool) *structpb.Struct {
+		st, err := structpb.NewStruct(map[string]interface{}{
+			"from_user_id": fromUserID,
+			"to_user_id":   toUserID,
+			"dry_run":      dryRun,
+		})
+		require.NoError(t, err)
+		return st
+	}
+	changedObjects := []interface{}{
+		"schedule:test-owned-schedule-guid",
+		"service:test-service-guid",
+		"functionality:test-functionality-guid",
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		_, actionStatus, result, _, err := manager.InvokeAction(ctx, transferOwnershipAction, args("100", "300", true))
+		require.NoError(t, err)
+		require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+		require.Equal(t, changedObjects, result.Fields["changed_objects"].GetListValue().AsSlice())
+		require.True(t, result.Fields["dry_run"].GetBoolValue())
+		require.Empty(t, updated())
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		id, actionStatus, result, _, err := manager.InvokeAction(ctx, transferOwnershipAction, args("100", "300", false))
+		require.NoError(t, err)
+		require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_COMPLETE, actionStatus)
+		require.Equal(t, "transfer_ownership:100:300", id)
+		require.Equal(t, changedObjects, result.Fields["changed_objects"].GetListValue().AsSlice())
+		require.False(t, result.Fields["dry_run"].GetBoolValue())
+
+		require.Equal(t, map[string]string{
+			"/v1/schedules/test-owned-schedule-guid": `{"data":{"type":"schedules","attributes":{"owner_user_id":300}}}`,
+			"/v1/services/test-service-guid":         `{"data":{"type":"services","attributes":{"owners_user_ids":[300,200]}}}`,
+			// user 300 owns the functionality already
+			"/v1/functionalities/test-functionality-guid": `{"data":{"type":"functionalities","attributes":{"owners_user_ids":[300]}}}`,
+		}, updated())
+		// the owners are read again after the dry run, rather than from the GET cache
+		for _, path := range []string{"/v1/users/300", "/v1/schedules", "/v1/services", "/v1/functionalities", "/v1/escalation_policies"} {
+			require.Equal(t, 2, fake.requestCount(path), path)
+		}
+	})
+
+	for _, tc := range []struct {
+		name string
+		args *structpb.Struct
+	}{
+		{"same user", args("100", "100", false)},
+		{"invalid user ID", args("100", "jane", false)},
+		{"unknown user", args("100", "400", false)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, actionStatus, _, _, err := manager.InvokeAction(ctx, transferOwnershipAction, tc.args)
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+			require.Equal(t, v2.BatonActionStatus_BATON_ACTION_STATUS_FAILED, actionStatus)
+		})
+	}
+	require.Len(t, updated(), 3)
+}
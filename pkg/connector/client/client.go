@@ -1,31 +1,68 @@
 package client
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
 
 const (
-	BaseURLStr                           = "https://api.rootly.com"
-	ListUsersAPIEndpoint                 = "/v1/users"
-	ListTeamsAPIEndpoint                 = "/v1/teams"
-	GetTeamAPIEndpoint                   = "/v1/teams/%s"
-	ListSecretsAPIEndpoint               = "/v1/secrets"
-	ListSchedulesAPIEndpoint             = "/v1/schedules"
-	GetScheduleAPIEndpoint               = "/v1/schedules/%s"
-	ListScheduleRotationsAPIEndpoint     = "/v1/schedules/%s/schedule_rotations"
-	ListScheduleRotationUsersAPIEndpoint = "/v1/schedule_rotations/%s/schedule_rotation_users"
-	ListScheduleShiftsAPIEndpoint        = "/v1/shifts"
-	ResourcesPageSize                    = 200
+	BaseURLStr                             = "https://api.rootly.com"
+	ListUsersAPIEndpoint                   = "/v1/users"
+	GetUserAPIEndpoint                     = "/v1/users/%s"
+	ListUserPhoneNumbersAPIEndpoint        = "/v1/users/%s/phone_numbers"
+	ListUserEmailAddressesAPIEndpoint      = "/v1/users/%s/email_addresses"
+	ListUserNotificationRulesAPIEndpoint   = "/v1/users/%s/notification_rules"
+	ListTeamsAPIEndpoint                   = "/v1/teams"
+	GetTeamAPIEndpoint                     = "/v1/teams/%s"
+	ListSecretsAPIEndpoint                 = "/v1/secrets"
+	GetSecretAPIEndpoint                   = "/v1/secrets/%s"
+	ListSchedulesAPIEndpoint               = "/v1/schedules"
+	GetScheduleAPIEndpoint                 = "/v1/schedules/%s"
+	ListScheduleRotationsAPIEndpoint       = "/v1/schedules/%s/schedule_rotations"
+	ListScheduleRotationUsersAPIEndpoint   = "/v1/schedule_rotations/%s/schedule_rotation_users"
+	ListScheduleShiftsAPIEndpoint          = "/v1/shifts"
+	CreateOverrideShiftAPIEndpoint         = "/v1/schedules/%s/override_shifts"
+	CreateAlertAPIEndpoint                 = "/v1/alerts"
+	GetAlertAPIEndpoint                    = "/v1/alerts/%s"
+	ListAlertUrgenciesAPIEndpoint          = "/v1/alert_urgencies"
+	UpdateScheduleAPIEndpoint              = "/v1/schedules/%s"
+	ListServicesAPIEndpoint                = "/v1/services"
+	UpdateServiceAPIEndpoint               = "/v1/services/%s"
+	ListFunctionalitiesAPIEndpoint         = "/v1/functionalities"
+	UpdateFunctionalityAPIEndpoint         = "/v1/functionalities/%s"
+	ListEscalationPoliciesAPIEndpoint      = "/v1/escalation_policies"
+	UpdateEscalationPolicyAPIEndpoint      = "/v1/escalation_policies/%s"
+	ListAuditsAPIEndpoint                  = "/v1/audits"
+	ListIncidentsAPIEndpoint               = "/v1/incidents"
+	ListIncidentEventsAPIEndpoint          = "/v1/incidents/%s/events"
+	ListIncidentRoleAssignmentsAPIEndpoint = "/v1/incidents/%s/incident_role_assignments"
+	GetIncidentAPIEndpoint                 = "/v1/incidents/%s"
+	CreateIncidentAPIEndpoint              = "/v1/incidents"
+	CreateFormFieldSelectionAPIEndpoint    = "/v1/incidents/%s/form_field_selections"
+	CreateActionItemAPIEndpoint            = "/v1/incidents/%s/action_items"
+	GetActionItemAPIEndpoint               = "/v1/action_items/%s"
+	ListSeveritiesAPIEndpoint              = "/v1/severities"
+	ListIncidentTypesAPIEndpoint           = "/v1/incident_types"
+	GetIncidentTypeAPIEndpoint             = "/v1/incident_types/%s"
+	ListFormFieldsAPIEndpoint              = "/v1/form_fields"
+	ListFormFieldOptionsAPIEndpoint        = "/v1/form_fields/%s/options"
+	ResourcesPageSize                      = 200
+	DefaultOnCallWindow                    = 1 * time.Hour
 )
 
 type Client struct {
@@ -33,10 +70,14 @@ type Client struct {
 	baseURL           *url.URL
 	apiKey            string
 	resourcesPageSize int
+	throttle          *rateLimitThrottle
+	retry             *retryPolicy
+	concurrency       int
+	uncached          bool
 }
 
 // NewClient creates a new Rootly client. Allows for a configurable base URL, API key, and resources page size.
-func NewClient(ctx context.Context, baseURL string, apiKey string, resourcesPageSize int) (*Client, error) {
+func NewClient(ctx context.Context, baseURL string, apiKey string, resourcesPageSize int, opts ...ClientOption) (*Client, error) {
 	httpClient, err := uhttp.NewBaseHttpClientWithContext(ctx, http.DefaultClient)
 	if err != nil {
 		return nil, err
@@ -59,22 +100,88 @@ func NewClient(ctx context.Context, baseURL string, apiKey string, resourcesPage
 	// This is preferred over using the regular http.Client directly
 	// as it provides automatic rate limiting handling, error wrapping with gRPC status codes,
 	// and built-in GET response caching
-	return &Client{
+	c := &Client{
 		httpClient:        httpClient,
 		baseURL:           parsedURL,
 		apiKey:            apiKey,
 		resourcesPageSize: resourcesPageSize,
-	}, nil
+		throttle:          newRateLimitThrottle(),
+		retry:             newRetryPolicy(),
+		concurrency:       DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Uncached returns a copy of the client whose GET requests bypass the GET cache of the HTTP client, for reads that
+// must reflect the current state, e.g. the preconditions checked before a write.
+func (c *Client) Uncached() *Client {
+	uncached := *c
+	uncached.uncached = true
+	return &uncached
 }
 
 // doRequest is a helper for taking various request inputs, issuing a client request, and handling the response.
 // It marshals the response body given a target.
+// GET requests, being idempotent, are retried with backoff on transient errors according to the retry policy, and
+// bypass the GET cache of the HTTP client when the client is uncached.
 func (c *Client) doRequest(
 	ctx context.Context,
 	method string,
 	url *url.URL,
 	requestBody interface{},
 	target interface{},
+) error {
+	if method != http.MethodGet {
+		return c.doRequestOnce(ctx, method, url, requestBody, target)
+	}
+
+	l := ctxzap.Extract(ctx)
+	start := c.retry.now()
+	for attempt := 1; ; attempt++ {
+		var err error
+		if c.uncached {
+			err = c.doUncachedGet(ctx, url, target)
+		} else {
+			err = c.doRequestOnce(ctx, method, url, requestBody, target)
+		}
+		if attempt >= c.retry.maxAttempts || !isRetryable(ctx, err) {
+			return err
+		}
+		delay := c.retry.backoff(attempt)
+		if c.retry.now().Add(delay).Sub(start) > c.retry.maxElapsed {
+			l.Warn(
+				"giving up retrying request, the retry time budget is exhausted",
+				zap.String("url", url.String()),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			return err
+		}
+		l.Warn(
+			"retrying request after a transient error",
+			zap.String("url", url.String()),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", c.retry.maxAttempts),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		err = c.retry.sleep(ctx, delay)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// doRequestOnce issues a single client request and handles the response.
+func (c *Client) doRequestOnce(
+	ctx context.Context,
+	method string,
+	url *url.URL,
+	requestBody interface{},
+	target interface{},
 ) error {
 	l := ctxzap.Extract(ctx)
 
@@ -84,18 +191,29 @@ func (c *Client) doRequest(
 		uhttp.WithAcceptVndJSONHeader(),
 	}
 	if requestBody != nil {
-		reqOptions = append(reqOptions, uhttp.WithJSONBody(requestBody))
+		// the Rootly API expects JSON:API request bodies
+		reqOptions = append(reqOptions, uhttp.WithJSONBody(requestBody), uhttp.WithContentTypeVndHeader())
 	}
 	req, err := c.httpClient.NewRequest(ctx, method, url, reqOptions...)
 	if err != nil {
 		return err
 	}
 
+	// slow down when the rate limit budget runs low
+	err = c.throttle.wait(ctx)
+	if err != nil {
+		return err
+	}
+
 	// do the request and handle the response
 	l.Debug("sending request", zap.String("url", url.String()), zap.String("method", method))
-	// Add error response handling
+	// Add error response handling, and record the rate limit budget left
 	var rootlyError RootlyErrorResponse
-	respOptions := []uhttp.DoOption{uhttp.WithErrorResponse(&rootlyError)}
+	rateLimit := &v2.RateLimitDescription{}
+	respOptions := []uhttp.DoOption{
+		uhttp.WithErrorResponse(&rootlyError),
+		uhttp.WithRatelimitData(rateLimit),
+	}
 	if target != nil {
 		respOptions = append(respOptions, uhttp.WithJSONResponse(target))
 	}
@@ -103,12 +221,114 @@ func (c *Client) doRequest(
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
+	if resp != nil {
+		c.throttle.observe(rateLimit)
+	}
+	if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		// keep the Rootly errors and status code, rather than uhttp's flattened message
+		return newAPIError(resp.StatusCode, rootlyError, rateLimit)
+	}
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// doUncachedGet is like doRequest for a GET request, but bypasses the GET cache of the HTTP client, for resources
+// polled for changes, e.g. the status of an alert. Called directly, it isn't retried, since the caller polls again
+// anyway.
+func (c *Client) doUncachedGet(ctx context.Context, url *url.URL, target interface{}) error {
+	req, err := c.httpClient.NewRequest(
+		ctx,
+		http.MethodGet,
+		url,
+		uhttp.WithBearerToken(c.apiKey),
+		uhttp.WithAcceptVndJSONHeader(),
+	)
+	if err != nil {
+		return err
+	}
+	err = c.throttle.wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctxzap.Extract(ctx).Debug("sending uncached request", zap.String("url", url.String()))
+	resp, err := c.httpClient.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	rateLimit, err := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header)
+	if err == nil {
+		c.throttle.observe(rateLimit)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var rootlyError RootlyErrorResponse
+		// the error body is best effort, the status code is enough to classify the error
+		_ = json.NewDecoder(resp.Body).Decode(&rootlyError)
+		return newAPIError(resp.StatusCode, rootlyError, rateLimit)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// GetImage downloads an image, e.g. the avatar of a user, returning its content type along with its content, which the
+// caller closes. The API key is only sent along when the image is hosted by the Rootly API, rather than e.g. a CDN.
+func (c *Client) GetImage(ctx context.Context, imageURL string) (string, io.ReadCloser, error) {
+	parsedURL, err := url.Parse(imageURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("get-image: %w", err)
+	}
+	parsedURL = c.baseURL.ResolveReference(parsedURL)
+	if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+		return "", nil, fmt.Errorf("get-image: unsupported URL scheme %q", parsedURL.Scheme)
+	}
+	var reqOptions []uhttp.RequestOption
+	if c.isAPIURL(parsedURL) {
+		reqOptions = append(reqOptions, uhttp.WithBearerToken(c.apiKey))
+	}
+	req, err := c.httpClient.NewRequest(ctx, http.MethodGet, parsedURL, reqOptions...)
+	if err != nil {
+		return "", nil, fmt.Errorf("get-image: %w", err)
+	}
+
+	ctxzap.Extract(ctx).Debug("downloading image", zap.String("url", parsedURL.String()))
+	resp, err := c.httpClient.HttpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("get-image: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return "", nil, fmt.Errorf("get-image: %w", newAPIError(resp.StatusCode, RootlyErrorResponse{}, nil))
+	}
+
+	// images served as generic binary data are sniffed for their actual type
+	body := bufio.NewReader(resp.Body)
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get(uhttp.ContentType))
+	if contentType == "" || contentType == "application/octet-stream" {
+		head, _ := body.Peek(512)
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		resp.Body.Close()
+		return "", nil, fmt.Errorf("get-image: %s isn't an image but %s", parsedURL.Redacted(), contentType)
+	}
+	return contentType, struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}, nil
+}
+
+// isAPIURL reports whether a URL points at the Rootly API, i.e. has the scheme and host of the base URL and is under
+// its path, so that the API key is only sent where requests to the API are sent.
+func (c *Client) isAPIURL(u *url.URL) bool {
+	if u.Scheme != c.baseURL.Scheme || u.Host != c.baseURL.Host {
+		return false
+	}
+	basePath := strings.TrimSuffix(c.baseURL.Path, "/")
+	return u.Path == basePath || strings.HasPrefix(u.Path, basePath+"/")
+}
+
 func (c *Client) generateURL(
 	path string,
 	queryParameters map[string]string,
@@ -130,6 +350,63 @@ func (c *Client) generateURL(
 	return output
 }
 
+// ListOption sets optional query parameters, such as filters, on the first request to a paginated endpoint.
+// Subsequent pages inherit them from the next link returned by the Rootly API.
+type ListOption func(queryParameters map[string]string)
+
+// WithName filters a list request to resources with exactly the given name.
+func WithName(name string) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["filter[name]"] = name
+	}
+}
+
+// WithSearch filters a list request to resources matching the given search text.
+func WithSearch(text string) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["filter[search]"] = text
+	}
+}
+
+// WithUpdatedSince filters a list request to resources updated at or after the given time.
+func WithUpdatedSince(since time.Time) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["filter[updated_at][gte]"] = since.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// WithStatuses filters a list request to resources with any of the given statuses.
+func WithStatuses(statuses ...string) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["filter[status]"] = strings.Join(statuses, ",")
+	}
+}
+
+// withPageSize overrides the number of resources requested per page, e.g. to honor the page size of an event stream.
+func withPageSize(size int) ListOption {
+	return func(queryParameters map[string]string) {
+		if size > 0 {
+			queryParameters["page[size]"] = strconv.Itoa(size)
+		}
+	}
+}
+
+// withInclude requests related resources to be included in a compound document, per the JSON:API spec.
+func withInclude(relationship string) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["include"] = relationship
+	}
+}
+
+// withCreatedWithin filters a list request to resources created from the given time, included, until the given time,
+// excluded.
+func withCreatedWithin(from time.Time, until time.Time) ListOption {
+	return func(queryParameters map[string]string) {
+		queryParameters["filter[created_at][gte]"] = from.UTC().Format(time.RFC3339Nano)
+		queryParameters["filter[created_at][lt]"] = until.UTC().Format(time.RFC3339Nano)
+	}
+}
+
 // generateCurrentPaginatedURL either parses the URL from the page token, or generates a new URL
 // with initial pagination if there's no token.
 func (c *Client) generateCurrentPaginatedURL(
@@ -137,12 +414,25 @@ func (c *Client) generateCurrentPaginatedURL(
 	pToken string,
 	path string,
 	pathParameters ...string,
+) (*url.URL, error) {
+	return c.generateCurrentFilteredPaginatedURL(ctx, pToken, path, nil, pathParameters...)
+}
+
+// generateCurrentFilteredPaginatedURL is like generateCurrentPaginatedURL, but applies the given list options
+// to the first paginated request.
+func (c *Client) generateCurrentFilteredPaginatedURL(
+	ctx context.Context,
+	pToken string,
+	path string,
+	opts []ListOption,
+	pathParameters ...string,
 ) (*url.URL, error) {
 	logger := ctxzap.Extract(ctx)
 	if pToken != "" {
 		// this is not the first request to this endpoint
-		// use the token string, ie a full URL with params already populated based on the prior request
-		parsedURL, err := url.Parse(pToken)
+		// use the token string, ie a full URL with params already populated based on the prior request,
+		// as long as it points to the base URL
+		parsedURL, err := c.parsePageToken(pToken)
 		if err != nil {
 			return nil, err
 		}
@@ -151,26 +441,55 @@ func (c *Client) generateCurrentPaginatedURL(
 	}
 
 	// otherwise this is the first paginated request to this endpoint
-	parsedURL := c.generateURL(
-		path,
-		map[string]string{
-			"page[number]": "1",
-			"page[size]":   strconv.Itoa(c.resourcesPageSize),
-		},
-		pathParameters...,
-	)
+	queryParameters := map[string]string{
+		"page[number]": "1",
+		"page[size]":   strconv.Itoa(c.resourcesPageSize),
+	}
+	for _, opt := range opts {
+		opt(queryParameters)
+	}
+	parsedURL := c.generateURL(path, queryParameters, pathParameters...)
 	logger.Debug("Generated first paginated URL", zap.String("parsedURL", parsedURL.String()))
 	return parsedURL, nil
 }
 
+// RateLimit returns the rate limit budget reported by the last Rootly API response with rate limit headers,
+// or nil if there was none.
+func (c *Client) RateLimit() *v2.RateLimitDescription {
+	return c.throttle.latest()
+}
+
 func (c *Client) IsTest() bool {
 	return c.apiKey == "test"
 }
 
-// GetUsers fetches users from the Rootly API. It supports pagination using a page token.
-func (c *Client) GetUsers(ctx context.Context, pToken string) ([]User, string, error) {
+// ProbeList requests a single resource from a list endpoint, e.g. ListSchedulesAPIEndpoint, to check that the API key
+// can read it. The returned error carries the gRPC code of the response status, e.g. codes.PermissionDenied for 403.
+func (c *Client) ProbeList(ctx context.Context, endpoint string) error {
+	parsedURL := c.generateURL(endpoint, map[string]string{
+		"page[number]": "1",
+		"page[size]":   "1",
+	})
+	ctxzap.Extract(ctx).Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("probe-list: %w", err)
+	}
+	return nil
+}
+
+// GetUsers fetches users from the Rootly API. It supports pagination using a page token,
+// and optional filters applied to the first page.
+func (c *Client) GetUsers(ctx context.Context, pToken string, opts ...ListOption) ([]User, string, error) {
 	logger := ctxzap.Extract(ctx)
-	parsedURL, err := c.generateCurrentPaginatedURL(ctx, pToken, ListUsersAPIEndpoint)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(ctx, pToken, ListUsersAPIEndpoint, opts)
 	if err != nil {
 		return nil, "", fmt.Errorf("get-users: %w", err)
 	}
@@ -190,10 +509,204 @@ func (c *Client) GetUsers(ctx context.Context, pToken string) ([]User, string, e
 	return resp.Data, resp.Links.Next, nil
 }
 
-// GetTeams fetches the teams from the Rootly API. It supports pagination using a page token.
-func (c *Client) GetTeams(ctx context.Context, pToken string) ([]Team, string, error) {
+// GetUser fetches a single user from the Rootly API.
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	logger := ctxzap.Extract(ctx)
+	if userID == "" {
+		logger.Error("get-user: userID is required")
+		return nil, fmt.Errorf("get-user: userID is required")
+	}
+	parsedURL := c.generateURL(GetUserAPIEndpoint, nil, userID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp UserResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get-user: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// ListAllUserPhoneNumbers returns all the phone numbers of a user.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllUserPhoneNumbers(ctx context.Context, userID string) ([]UserPhoneNumber, error) {
+	logger := ctxzap.Extract(ctx)
+	if userID == "" {
+		logger.Error("list-all-user-phone-numbers: userID is required")
+		return nil, fmt.Errorf("list-all-user-phone-numbers: userID is required")
+	}
+	var phoneNumbers []UserPhoneNumber
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListUserPhoneNumbersAPIEndpoint, userID)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-user-phone-numbers: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp UserPhoneNumbersResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-user-phone-numbers: %w", err)
+		}
+		phoneNumbers = append(phoneNumbers, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return phoneNumbers, nil
+}
+
+// ListAllUserEmailAddresses returns all the email addresses of a user.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllUserEmailAddresses(ctx context.Context, userID string) ([]UserEmailAddress, error) {
+	logger := ctxzap.Extract(ctx)
+	if userID == "" {
+		logger.Error("list-all-user-email-addresses: userID is required")
+		return nil, fmt.Errorf("list-all-user-email-addresses: userID is required")
+	}
+	var emailAddresses []UserEmailAddress
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListUserEmailAddressesAPIEndpoint, userID)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-user-email-addresses: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp UserEmailAddressesResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-user-email-addresses: %w", err)
+		}
+		emailAddresses = append(emailAddresses, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return emailAddresses, nil
+}
+
+// CountUserNotificationRules returns the number of notification rules of a user.
+// It uses pagination under the hood to make one or more requests to count them all.
+func (c *Client) CountUserNotificationRules(ctx context.Context, userID string) (int, error) {
+	logger := ctxzap.Extract(ctx)
+	if userID == "" {
+		logger.Error("count-user-notification-rules: userID is required")
+		return 0, fmt.Errorf("count-user-notification-rules: userID is required")
+	}
+	var count int
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListUserNotificationRulesAPIEndpoint, userID)
+		if err != nil {
+			return 0, fmt.Errorf("count-user-notification-rules: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp UserNotificationRulesResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("count-user-notification-rules: %w", err)
+		}
+		count += len(resp.Data)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return count, nil
+}
+
+// GetUserContacts returns the verified contact methods and the notification rules of a user.
+func (c *Client) GetUserContacts(ctx context.Context, userID string) (*UserContacts, error) {
+	contacts := &UserContacts{UserID: userID}
+	phoneNumbers, err := c.ListAllUserPhoneNumbers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get-user-contacts: %w", err)
+	}
+	for _, phoneNumber := range phoneNumbers {
+		if phoneNumber.Attributes.VerifiedAt != "" {
+			contacts.VerifiedPhoneNumbers++
+		}
+	}
+	emailAddresses, err := c.ListAllUserEmailAddresses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get-user-contacts: %w", err)
+	}
+	for _, emailAddress := range emailAddresses {
+		if emailAddress.Attributes.VerifiedAt != "" {
+			contacts.VerifiedEmailAddresses++
+		}
+	}
+	contacts.NotificationRules, err = c.CountUserNotificationRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get-user-contacts: %w", err)
+	}
+	contacts.Complete = true
+	return contacts, nil
+}
+
+// ListAllUserContactsConcurrently returns the contacts of the given users, in the same order, fetching those of
+// several users at once. The contacts of a user that can't be read, e.g. because the user was deleted meanwhile or the
+// API key isn't allowed to, are incomplete rather than failing the others.
+func (c *Client) ListAllUserContactsConcurrently(ctx context.Context, userIDs []string) ([]UserContacts, error) {
+	contacts := make([]UserContacts, len(userIDs))
+	err := c.forEachConcurrently(ctx, len(userIDs), func(ctx context.Context, i int) error {
+		userContacts, err := c.GetUserContacts(ctx, userIDs[i])
+		switch {
+		case err == nil:
+			contacts[i] = *userContacts
+		case IsNotFound(err) || IsForbidden(err):
+			contacts[i] = UserContacts{UserID: userIDs[i]}
+		default:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list-all-user-contacts-concurrently: %w", err)
+	}
+	return contacts, nil
+}
+
+// GetTeams fetches the teams from the Rootly API. It supports pagination using a page token,
+// and optional filters applied to the first page.
+func (c *Client) GetTeams(ctx context.Context, pToken string, opts ...ListOption) ([]Team, string, error) {
 	logger := ctxzap.Extract(ctx)
-	parsedURL, err := c.generateCurrentPaginatedURL(ctx, pToken, ListTeamsAPIEndpoint)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(ctx, pToken, ListTeamsAPIEndpoint, opts)
 	if err != nil {
 		return nil, "", fmt.Errorf("get-teams: %w", err)
 	}
@@ -213,15 +726,12 @@ func (c *Client) GetTeams(ctx context.Context, pToken string) ([]Team, string, e
 	return resp.Data, resp.Links.Next, nil
 }
 
-// GetTeamMemberAndAdminIDs returns a list of member user IDs and admin user IDs for a given team ID.
-func (c *Client) GetTeamMemberAndAdminIDs(
-	ctx context.Context,
-	teamID string,
-) ([]int, []int, error) {
+// GetTeam fetches a single team from the Rootly API, including its member and admin user IDs.
+func (c *Client) GetTeam(ctx context.Context, teamID string) (*Team, error) {
 	logger := ctxzap.Extract(ctx)
 	if teamID == "" {
-		logger.Error("get-team-member-and-admin-ids: teamID is required")
-		return nil, nil, fmt.Errorf("get-team-member-and-admin-ids: teamID is required")
+		logger.Error("get-team: teamID is required")
+		return nil, fmt.Errorf("get-team: teamID is required")
 	}
 	parsedURL := c.generateURL(GetTeamAPIEndpoint, nil, teamID)
 	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
@@ -234,11 +744,49 @@ func (c *Client) GetTeamMemberAndAdminIDs(
 		nil,
 		&resp,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("get-team: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetTeamMemberAndAdminIDs returns a list of member user IDs and admin user IDs for a given team ID.
+func (c *Client) GetTeamMemberAndAdminIDs(
+	ctx context.Context,
+	teamID string,
+) ([]int, []int, error) {
+	team, err := c.GetTeam(ctx, teamID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get-team-member-and-admin-ids: %w", err)
 	}
 
-	return resp.Data.Attributes.UserIDs, resp.Data.Attributes.AdminIDs, nil
+	return team.Attributes.UserIDs, team.Attributes.AdminIDs, nil
+}
+
+// GetTeamsMemberAndAdminIDs returns the member and admin user IDs of each of the given team IDs, in the same order.
+// The teams are fetched concurrently, and those that no longer exist are returned as deleted.
+func (c *Client) GetTeamsMemberAndAdminIDs(
+	ctx context.Context,
+	teamIDs []string,
+) ([]TeamMembers, error) {
+	teams := make([]TeamMembers, len(teamIDs))
+	err := c.forEachConcurrently(ctx, len(teamIDs), func(ctx context.Context, i int) error {
+		memberIDs, adminIDs, err := c.GetTeamMemberAndAdminIDs(ctx, teamIDs[i])
+		teams[i] = TeamMembers{
+			TeamID:   teamIDs[i],
+			UserIDs:  memberIDs,
+			AdminIDs: adminIDs,
+			Deleted:  IsNotFound(err),
+		}
+		if err != nil && !teams[i].Deleted {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get-teams-member-and-admin-ids: %w", err)
+	}
+	return teams, nil
 }
 
 // GetSecrets fetches the secrets from the Rootly API. It supports pagination using a page token.
@@ -264,10 +812,35 @@ func (c *Client) GetSecrets(ctx context.Context, pToken string) ([]Secret, strin
 	return resp.Data, resp.Links.Next, nil
 }
 
-// GetSchedules fetches the schedules from the Rootly API. It supports pagination using a page token.
-func (c *Client) GetSchedules(ctx context.Context, pToken string) ([]Schedule, string, error) {
+// GetSecret fetches a single secret from the Rootly API.
+func (c *Client) GetSecret(ctx context.Context, secretID string) (*Secret, error) {
 	logger := ctxzap.Extract(ctx)
-	parsedURL, err := c.generateCurrentPaginatedURL(ctx, pToken, ListSchedulesAPIEndpoint)
+	if secretID == "" {
+		logger.Error("get-secret: secretID is required")
+		return nil, fmt.Errorf("get-secret: secretID is required")
+	}
+	parsedURL := c.generateURL(GetSecretAPIEndpoint, nil, secretID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp SecretResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get-secret: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetSchedules fetches the schedules from the Rootly API. It supports pagination using a page token,
+// and optional filters applied to the first page.
+func (c *Client) GetSchedules(ctx context.Context, pToken string, opts ...ListOption) ([]Schedule, string, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(ctx, pToken, ListSchedulesAPIEndpoint, opts)
 	if err != nil {
 		return nil, "", fmt.Errorf("get-schedules: %w", err)
 	}
@@ -287,15 +860,12 @@ func (c *Client) GetSchedules(ctx context.Context, pToken string) ([]Schedule, s
 	return resp.Data, resp.Links.Next, nil
 }
 
-// GetScheduleOwnerIDs returns an owner user ID and a list of owner team IDs for a given schedule ID.
-func (c *Client) GetScheduleOwnerIDs(
-	ctx context.Context,
-	scheduleID string,
-) (*int, []string, error) {
+// GetSchedule fetches a single schedule from the Rootly API, including its owners.
+func (c *Client) GetSchedule(ctx context.Context, scheduleID string) (*Schedule, error) {
 	logger := ctxzap.Extract(ctx)
 	if scheduleID == "" {
-		logger.Error("get-schedule-owner-ids: scheduleID is required")
-		return nil, nil, fmt.Errorf("get-schedule-owner-ids: scheduleID is required")
+		logger.Error("get-schedule: scheduleID is required")
+		return nil, fmt.Errorf("get-schedule: scheduleID is required")
 	}
 	parsedURL := c.generateURL(GetScheduleAPIEndpoint, nil, scheduleID)
 	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
@@ -308,11 +878,23 @@ func (c *Client) GetScheduleOwnerIDs(
 		nil,
 		&resp,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("get-schedule: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetScheduleOwnerIDs returns an owner user ID and a list of owner team IDs for a given schedule ID.
+func (c *Client) GetScheduleOwnerIDs(
+	ctx context.Context,
+	scheduleID string,
+) (*int, []string, error) {
+	schedule, err := c.GetSchedule(ctx, scheduleID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get-schedule-owner-ids: %w", err)
 	}
 
-	return resp.Data.Attributes.OwnerUserID, resp.Data.Attributes.OwnerGroupIDs, nil
+	return schedule.Attributes.OwnerUserID, schedule.Attributes.OwnerGroupIDs, nil
 }
 
 // ListScheduleRotations returns a list of schedule rotation IDs for a given schedule ID.
@@ -357,9 +939,122 @@ func (c *Client) ListScheduleRotations(
 	return rotationIDs, resp.Links.Next, nil
 }
 
-// ListScheduleRotationUsers returns a list of user IDs for a given schedule rotation ID.
-// It supports pagination using a page token.
-func (c *Client) ListScheduleRotationUsers(
+// ListAllScheduleRotations returns all the rotations of a given schedule ID, along with the time they were last
+// updated. It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllScheduleRotations(ctx context.Context, scheduleID string) ([]ScheduleRotation, error) {
+	logger := ctxzap.Extract(ctx)
+	if scheduleID == "" {
+		logger.Error("list-all-schedule-rotations: scheduleID is required")
+		return nil, fmt.Errorf("list-all-schedule-rotations: scheduleID is required")
+	}
+	var rotations []ScheduleRotation
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListScheduleRotationsAPIEndpoint, scheduleID)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-schedule-rotations: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp ScheduleRotationsResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-schedule-rotations: %w", err)
+		}
+		rotations = append(rotations, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return rotations, nil
+}
+
+// ListScheduleRotationsWithUsers returns the schedule rotations for a given schedule ID along with their member
+// user IDs, using a single request per page that includes the schedule rotation users. Rotations whose included
+// users were truncated are returned as incomplete, so their members can be listed with ListAllScheduleRotationUsers.
+// It supports pagination using a page token.
+func (c *Client) ListScheduleRotationsWithUsers(
+	ctx context.Context,
+	scheduleID string,
+	pToken string,
+) ([]ScheduleRotationMembers, string, error) {
+	logger := ctxzap.Extract(ctx)
+	if scheduleID == "" {
+		logger.Error("list-schedule-rotations-with-users: scheduleID is required")
+		return nil, "", fmt.Errorf("list-schedule-rotations-with-users: scheduleID is required")
+	}
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListScheduleRotationsAPIEndpoint,
+		[]ListOption{withInclude("schedule_rotation_users")},
+		scheduleID,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-schedule-rotations-with-users: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp ScheduleRotationsWithUsersResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-schedule-rotations-with-users: %w", err)
+	}
+
+	includedUserIDs := make(map[string]int)
+	for _, user := range resp.Included {
+		if user.Type != "schedule_rotation_users" {
+			logger.Debug("Unexpected type in schedule rotation included users", zap.String("user.Type", user.Type))
+			continue
+		}
+		includedUserIDs[user.ID] = user.Attributes.UserID
+	}
+
+	var rotations []ScheduleRotationMembers
+	for _, rotation := range resp.Data {
+		if rotation.Type != "schedule_rotations" {
+			logger.Debug("Unexpected type in schedule rotation", zap.String("rotation.Type", rotation.Type))
+			continue
+		}
+		members := ScheduleRotationMembers{
+			RotationID: rotation.ID,
+			// without the relationship linkage there's no telling whether the included users are complete
+			Complete: rotation.Relationships.ScheduleRotationUsers.Data != nil,
+		}
+		for _, rotationUser := range rotation.Relationships.ScheduleRotationUsers.Data {
+			userID, ok := includedUserIDs[rotationUser.ID]
+			if !ok {
+				// the included section was truncated
+				members.Complete = false
+				members.UserIDs = nil
+				break
+			}
+			members.UserIDs = append(members.UserIDs, userID)
+		}
+		rotations = append(rotations, members)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return rotations, resp.Links.Next, nil
+}
+
+// ListScheduleRotationUsers returns a list of user IDs for a given schedule rotation ID.
+// It supports pagination using a page token.
+func (c *Client) ListScheduleRotationUsers(
 	ctx context.Context,
 	rotationID string,
 	pToken string,
@@ -431,27 +1126,115 @@ func (c *Client) ListAllScheduleRotationUsers(
 	return userIDs, nil
 }
 
-// ListOnCallUsers returns a list of on-call user IDs for a given schedule ID.
-func (c *Client) ListOnCallUsers(
+// ListAllScheduleRotationUsersConcurrently returns all the member user IDs of each of the given schedule rotation IDs,
+// in the same order. The rotations are fetched concurrently, and those that no longer exist are returned as deleted.
+func (c *Client) ListAllScheduleRotationUsersConcurrently(
+	ctx context.Context,
+	rotationIDs []string,
+) ([]ScheduleRotationMembers, error) {
+	rotations := make([]ScheduleRotationMembers, len(rotationIDs))
+	err := c.forEachConcurrently(ctx, len(rotationIDs), func(ctx context.Context, i int) error {
+		userIDs, err := c.ListAllScheduleRotationUsers(ctx, rotationIDs[i])
+		rotations[i] = ScheduleRotationMembers{
+			RotationID: rotationIDs[i],
+			UserIDs:    userIDs,
+			Complete:   err == nil,
+			Deleted:    IsNotFound(err),
+		}
+		if err != nil && !rotations[i].Deleted {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list-all-schedule-rotation-users-concurrently: %w", err)
+	}
+	return rotations, nil
+}
+
+// ListAllScheduleMemberIDs returns the de-duplicated user IDs of the members of all the rotations of a given schedule
+// ID. It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllScheduleMemberIDs(ctx context.Context, scheduleID string) ([]int, error) {
+	var userIDs []int
+	seen := make(map[int]bool)
+	addMembers := func(memberIDs []int) {
+		for _, userID := range memberIDs {
+			if !seen[userID] {
+				seen[userID] = true
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+
+	var currentPage string
+	for {
+		rotations, nextPage, err := c.ListScheduleRotationsWithUsers(ctx, scheduleID, currentPage)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-schedule-member-ids: %w", err)
+		}
+		var truncatedRotationIDs []string
+		for _, rotation := range rotations {
+			if rotation.Complete {
+				addMembers(rotation.UserIDs)
+				continue
+			}
+			truncatedRotationIDs = append(truncatedRotationIDs, rotation.RotationID)
+		}
+		truncatedRotations, err := c.ListAllScheduleRotationUsersConcurrently(ctx, truncatedRotationIDs)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-schedule-member-ids: %w", err)
+		}
+		for _, rotation := range truncatedRotations {
+			addMembers(rotation.UserIDs)
+		}
+
+		currentPage = nextPage
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return userIDs, nil
+}
+
+// ListOnCallShifts returns the shifts of a given schedule ID that overlap the window from now until now plus
+// the given duration. It supports pagination using a page token, which also preserves the original window.
+func (c *Client) ListOnCallShifts(
 	ctx context.Context,
 	scheduleID string,
-) ([]int, error) {
+	window time.Duration,
+	pToken string,
+) ([]OnCallShift, string, error) {
 	logger := ctxzap.Extract(ctx)
 	if scheduleID == "" {
-		logger.Error("list-on-call-users: scheduleID is required")
-		return nil, fmt.Errorf("list-on-call-users: scheduleID is required")
+		logger.Error("list-on-call-shifts: scheduleID is required")
+		return nil, "", fmt.Errorf("list-on-call-shifts: scheduleID is required")
+	}
+	if window <= 0 {
+		window = DefaultOnCallWindow
 	}
 	now := time.Now().UTC()
-	parsedURL := c.generateURL(ListScheduleShiftsAPIEndpoint, map[string]string{
-		"include":        "user",
-		"schedule_ids[]": scheduleID,
-		"from":           now.Format(time.RFC3339),
-		"to":             now.Add(1 * time.Hour).Format(time.RFC3339),
-	}, scheduleID)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListScheduleShiftsAPIEndpoint,
+		[]ListOption{
+			// including the users makes sure the user relationship linkage of each shift is populated
+			withInclude("user"),
+			func(queryParameters map[string]string) {
+				queryParameters["schedule_ids[]"] = scheduleID
+				queryParameters["from"] = now.Format(time.RFC3339)
+				queryParameters["to"] = now.Add(window).Format(time.RFC3339)
+			},
+		},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-on-call-shifts: %w", err)
+	}
 	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
 
 	var resp ScheduleShiftsResponse
-	err := c.doRequest(
+	err = c.doRequest(
 		ctx,
 		http.MethodGet,
 		parsedURL,
@@ -459,21 +1242,945 @@ func (c *Client) ListOnCallUsers(
 		&resp,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("list-on-call-users: %w", err)
+		return nil, "", fmt.Errorf("list-on-call-shifts: %w", err)
 	}
 
-	var userIDs []int
-	for _, user := range resp.Included {
-		if user.Type != "users" {
-			logger.Debug("Unexpected type in on-call included users", zap.String("user.Type", user.Type))
+	shifts, err := newOnCallShifts(ctx, resp.Data)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-on-call-shifts: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return shifts, resp.Links.Next, nil
+}
+
+// ListShifts returns the shifts of all the schedules, including override shifts, that overlap the window from the
+// given time until the other, requesting the given number of shifts per page, or the client page size if it's not
+// positive. It supports pagination using a page token, which also preserves the original window.
+func (c *Client) ListShifts(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	pageSize int,
+	pToken string,
+) ([]OnCallShift, string, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListScheduleShiftsAPIEndpoint,
+		[]ListOption{
+			withPageSize(pageSize),
+			// including the users makes sure the user relationship linkage of each shift is populated
+			withInclude("user"),
+			func(queryParameters map[string]string) {
+				queryParameters["from"] = from.UTC().Format(time.RFC3339)
+				queryParameters["to"] = to.UTC().Format(time.RFC3339)
+			},
+		},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-shifts: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp ScheduleShiftsResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-shifts: %w", err)
+	}
+
+	shifts, err := newOnCallShifts(ctx, resp.Data)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-shifts: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return shifts, resp.Links.Next, nil
+}
+
+// newOnCallShifts returns the on-call shifts of the given schedule shifts, skipping those without a user.
+func newOnCallShifts(ctx context.Context, scheduleShifts []ScheduleShift) ([]OnCallShift, error) {
+	logger := ctxzap.Extract(ctx)
+	var shifts []OnCallShift
+	for _, shift := range scheduleShifts {
+		user := shift.Relationships.User.Data
+		if user == nil || user.Type != "users" {
+			logger.Debug("Shift without a user", zap.String("shift.ID", shift.ID))
 			continue
 		}
 		userID, err := strconv.Atoi(user.ID)
 		if err != nil {
-			return nil, fmt.Errorf("list-on-call-users: %w", err)
+			return nil, err
+		}
+		shifts = append(shifts, OnCallShift{
+			ShiftID:    shift.ID,
+			ScheduleID: shift.Attributes.ScheduleID,
+			UserID:     userID,
+			StartsAt:   shift.Attributes.StartsAt,
+			EndsAt:     shift.Attributes.EndsAt,
+			IsOverride: shift.Attributes.IsOverride,
+		})
+	}
+	return shifts, nil
+}
+
+// ListAllOnCallShifts returns all the shifts of a given schedule ID that overlap the window from now until now plus
+// the given duration. It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllOnCallShifts(
+	ctx context.Context,
+	scheduleID string,
+	window time.Duration,
+) ([]OnCallShift, error) {
+	logger := ctxzap.Extract(ctx)
+	if scheduleID == "" {
+		logger.Error("list-all-on-call-shifts: scheduleID is required")
+		return nil, fmt.Errorf("list-all-on-call-shifts: scheduleID is required")
+	}
+	var shifts []OnCallShift
+	var currentPage string
+	for {
+		pageShifts, nextPage, err := c.ListOnCallShifts(ctx, scheduleID, window, currentPage)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-on-call-shifts: %w", err)
+		}
+
+		logger.Debug(
+			"On-call shifts",
+			zap.Int("number of shifts", len(pageShifts)),
+			zap.String("nextPage", nextPage),
+		)
+
+		currentPage = nextPage
+		shifts = append(shifts, pageShifts...)
+
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return shifts, nil
+}
+
+// ListOnCallUsers returns a de-duplicated list of user IDs on-call for a given schedule ID,
+// at any time within the window from now until now plus the given duration.
+func (c *Client) ListOnCallUsers(
+	ctx context.Context,
+	scheduleID string,
+	window time.Duration,
+) ([]int, error) {
+	shifts, err := c.ListAllOnCallShifts(ctx, scheduleID, window)
+	if err != nil {
+		return nil, fmt.Errorf("list-on-call-users: %w", err)
+	}
+
+	var userIDs []int
+	seen := make(map[int]bool)
+	for _, shift := range shifts {
+		if seen[shift.UserID] {
+			continue
 		}
-		userIDs = append(userIDs, userID)
+		seen[shift.UserID] = true
+		userIDs = append(userIDs, shift.UserID)
 	}
 
 	return userIDs, nil
 }
+
+// ListAudits returns the audit log entries created from the given time until now, oldest first, requesting the given
+// number of entries per page, or the client page size if it's not positive. It supports pagination using a page
+// token, which also preserves the original time window.
+func (c *Client) ListAudits(
+	ctx context.Context,
+	createdAtOrAfter time.Time,
+	pageSize int,
+	pToken string,
+) ([]Audit, string, error) {
+	logger := ctxzap.Extract(ctx)
+	now := time.Now().UTC()
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListAuditsAPIEndpoint,
+		[]ListOption{
+			withPageSize(pageSize),
+			func(queryParameters map[string]string) {
+				queryParameters["filter[created_at][gte]"] = createdAtOrAfter.UTC().Format(time.RFC3339Nano)
+				// bounding the window by now also keeps successive polls from being served by the GET cache
+				queryParameters["filter[created_at][lte]"] = now.Format(time.RFC3339Nano)
+				queryParameters["sort"] = "created_at"
+			},
+		},
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-audits: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp AuditsResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-audits: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return resp.Data, resp.Links.Next, nil
+}
+
+// ListIncidents returns the incidents matching the given options, e.g. WithUpdatedSince, in creation order so that
+// incidents updated while paging don't move between pages, requesting the given number of incidents per page, or the
+// client page size if it's not positive. It supports pagination using a page token.
+func (c *Client) ListIncidents(
+	ctx context.Context,
+	pageSize int,
+	pToken string,
+	opts ...ListOption,
+) ([]Incident, string, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListIncidentsAPIEndpoint,
+		append([]ListOption{
+			withPageSize(pageSize),
+			func(queryParameters map[string]string) {
+				queryParameters["sort"] = "created_at"
+			},
+		}, opts...),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incidents: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentsResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incidents: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return resp.Data, resp.Links.Next, nil
+}
+
+// ListIncidentEvents returns the timeline events of a given incident ID created within the given time window.
+// It supports pagination using a page token.
+func (c *Client) ListIncidentEvents(
+	ctx context.Context,
+	incidentID string,
+	from time.Time,
+	until time.Time,
+	pToken string,
+) ([]IncidentEvent, string, error) {
+	logger := ctxzap.Extract(ctx)
+	if incidentID == "" {
+		logger.Error("list-incident-events: incidentID is required")
+		return nil, "", fmt.Errorf("list-incident-events: incidentID is required")
+	}
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListIncidentEventsAPIEndpoint,
+		[]ListOption{withCreatedWithin(from, until)},
+		incidentID,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-events: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentEventsResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-events: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return resp.Data, resp.Links.Next, nil
+}
+
+// ListAllIncidentEvents returns all the timeline events of a given incident ID created within the given time window.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllIncidentEvents(
+	ctx context.Context,
+	incidentID string,
+	from time.Time,
+	until time.Time,
+) ([]IncidentEvent, error) {
+	var events []IncidentEvent
+	var currentPage string
+	for {
+		pageEvents, nextPage, err := c.ListIncidentEvents(ctx, incidentID, from, until, currentPage)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-incident-events: %w", err)
+		}
+		currentPage = nextPage
+		events = append(events, pageEvents...)
+
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+// ListIncidentRoleAssignments returns the incident role assignments of a given incident ID created within the given
+// time window. It supports pagination using a page token.
+func (c *Client) ListIncidentRoleAssignments(
+	ctx context.Context,
+	incidentID string,
+	from time.Time,
+	until time.Time,
+	pToken string,
+) ([]IncidentRoleAssignment, string, error) {
+	logger := ctxzap.Extract(ctx)
+	if incidentID == "" {
+		logger.Error("list-incident-role-assignments: incidentID is required")
+		return nil, "", fmt.Errorf("list-incident-role-assignments: incidentID is required")
+	}
+	parsedURL, err := c.generateCurrentFilteredPaginatedURL(
+		ctx,
+		pToken,
+		ListIncidentRoleAssignmentsAPIEndpoint,
+		[]ListOption{withCreatedWithin(from, until)},
+		incidentID,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-role-assignments: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentRoleAssignmentsResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-role-assignments: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return resp.Data, resp.Links.Next, nil
+}
+
+// ListAllIncidentRoleAssignments returns all the incident role assignments of a given incident ID created within the
+// given time window. It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllIncidentRoleAssignments(
+	ctx context.Context,
+	incidentID string,
+	from time.Time,
+	until time.Time,
+) ([]IncidentRoleAssignment, error) {
+	var assignments []IncidentRoleAssignment
+	var currentPage string
+	for {
+		pageAssignments, nextPage, err := c.ListIncidentRoleAssignments(ctx, incidentID, from, until, currentPage)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-incident-role-assignments: %w", err)
+		}
+		currentPage = nextPage
+		assignments = append(assignments, pageAssignments...)
+
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return assignments, nil
+}
+
+// CreateOverrideShift creates an override shift of a given schedule ID, during which the given user ID is on-call
+// instead of the users of the schedule rotations.
+func (c *Client) CreateOverrideShift(
+	ctx context.Context,
+	scheduleID string,
+	userID int,
+	startsAt time.Time,
+	endsAt time.Time,
+) (*OverrideShift, error) {
+	logger := ctxzap.Extract(ctx)
+	if scheduleID == "" {
+		logger.Error("create-override-shift: scheduleID is required")
+		return nil, fmt.Errorf("create-override-shift: scheduleID is required")
+	}
+	parsedURL := c.generateURL(CreateOverrideShiftAPIEndpoint, nil, scheduleID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req OverrideShiftRequest
+	req.Data.Type = "override_shifts"
+	req.Data.Attributes = OverrideShiftRequestAttributes{
+		UserID:   userID,
+		StartsAt: startsAt.UTC().Format(time.RFC3339),
+		EndsAt:   endsAt.UTC().Format(time.RFC3339),
+	}
+	var resp OverrideShiftResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create-override-shift: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// CreateAlert creates an alert paging the on-call users of its notification target, e.g. a schedule.
+func (c *Client) CreateAlert(ctx context.Context, attributes AlertRequestAttributes) (*Alert, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL := c.generateURL(CreateAlertAPIEndpoint, nil)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req AlertRequest
+	req.Data.Type = "alerts"
+	req.Data.Attributes = attributes
+	var resp AlertResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create-alert: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetAlert fetches the current state of an alert from the Rootly API, bypassing the GET cache since alerts are
+// polled for their acknowledgement.
+func (c *Client) GetAlert(ctx context.Context, alertID string) (*Alert, error) {
+	logger := ctxzap.Extract(ctx)
+	if alertID == "" {
+		logger.Error("get-alert: alertID is required")
+		return nil, fmt.Errorf("get-alert: alertID is required")
+	}
+	parsedURL := c.generateURL(GetAlertAPIEndpoint, nil, alertID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp AlertResponse
+	err := c.doUncachedGet(ctx, parsedURL, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("get-alert: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetIncident returns an incident. The incident is always fetched from Rootly, since its status is polled for changes.
+func (c *Client) GetIncident(ctx context.Context, incidentID string) (*Incident, error) {
+	logger := ctxzap.Extract(ctx)
+	if incidentID == "" {
+		logger.Error("get-incident: incidentID is required")
+		return nil, fmt.Errorf("get-incident: incidentID is required")
+	}
+	parsedURL := c.generateURL(GetIncidentAPIEndpoint, nil, incidentID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentResponse
+	err := c.doUncachedGet(ctx, parsedURL, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("get-incident: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// CreateIncident creates an incident.
+func (c *Client) CreateIncident(ctx context.Context, attributes IncidentRequestAttributes) (*Incident, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL := c.generateURL(CreateIncidentAPIEndpoint, nil)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req IncidentRequest
+	req.Data.Type = "incidents"
+	req.Data.Attributes = attributes
+	var resp IncidentResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create-incident: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// CreateFormFieldSelection sets the value of a custom form field of an incident.
+func (c *Client) CreateFormFieldSelection(
+	ctx context.Context,
+	incidentID string,
+	attributes FormFieldSelectionRequestAttributes,
+) error {
+	logger := ctxzap.Extract(ctx)
+	if incidentID == "" {
+		logger.Error("create-form-field-selection: incidentID is required")
+		return fmt.Errorf("create-form-field-selection: incidentID is required")
+	}
+	parsedURL := c.generateURL(CreateFormFieldSelectionAPIEndpoint, nil, incidentID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req FormFieldSelectionRequest
+	req.Data.Type = "incident_form_field_selections"
+	req.Data.Attributes = attributes
+	var resp json.RawMessage
+	err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return fmt.Errorf("create-form-field-selection: %w", err)
+	}
+	return nil
+}
+
+// CreateActionItem creates a task action item of an incident.
+func (c *Client) CreateActionItem(
+	ctx context.Context,
+	incidentID string,
+	attributes ActionItemRequestAttributes,
+) (*ActionItem, error) {
+	logger := ctxzap.Extract(ctx)
+	if incidentID == "" {
+		logger.Error("create-action-item: incidentID is required")
+		return nil, fmt.Errorf("create-action-item: incidentID is required")
+	}
+	parsedURL := c.generateURL(CreateActionItemAPIEndpoint, nil, incidentID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req ActionItemRequest
+	req.Data.Type = "incident_action_items"
+	req.Data.Attributes = attributes
+	if req.Data.Attributes.Kind == "" {
+		req.Data.Attributes.Kind = "task"
+	}
+	var resp ActionItemResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create-action-item: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// GetActionItem returns an action item. The action item is always fetched from Rootly, since its status is polled for
+// changes.
+func (c *Client) GetActionItem(ctx context.Context, actionItemID string) (*ActionItem, error) {
+	logger := ctxzap.Extract(ctx)
+	if actionItemID == "" {
+		logger.Error("get-action-item: actionItemID is required")
+		return nil, fmt.Errorf("get-action-item: actionItemID is required")
+	}
+	parsedURL := c.generateURL(GetActionItemAPIEndpoint, nil, actionItemID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp ActionItemResponse
+	err := c.doUncachedGet(ctx, parsedURL, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("get-action-item: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// ListAllSeverities returns all the incident severities.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllSeverities(ctx context.Context) ([]Severity, error) {
+	logger := ctxzap.Extract(ctx)
+	var severities []Severity
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListSeveritiesAPIEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-severities: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp SeveritiesResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-severities: %w", err)
+		}
+		severities = append(severities, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return severities, nil
+}
+
+// ListIncidentTypes returns a page of incident types, along with the link to the next page, if any.
+func (c *Client) ListIncidentTypes(ctx context.Context, pToken string) ([]IncidentType, string, error) {
+	logger := ctxzap.Extract(ctx)
+	parsedURL, err := c.generateCurrentPaginatedURL(ctx, pToken, ListIncidentTypesAPIEndpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-types: %w", err)
+	}
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentTypesResponse
+	err = c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("list-incident-types: %w", err)
+	}
+	logger.Debug("Paginated URL for the next request", zap.String("resp.Links.Next", resp.Links.Next))
+	return resp.Data, resp.Links.Next, nil
+}
+
+// GetIncidentType returns an incident type.
+func (c *Client) GetIncidentType(ctx context.Context, incidentTypeID string) (*IncidentType, error) {
+	logger := ctxzap.Extract(ctx)
+	if incidentTypeID == "" {
+		logger.Error("get-incident-type: incidentTypeID is required")
+		return nil, fmt.Errorf("get-incident-type: incidentTypeID is required")
+	}
+	parsedURL := c.generateURL(GetIncidentTypeAPIEndpoint, nil, incidentTypeID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var resp IncidentTypeResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		parsedURL,
+		nil,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get-incident-type: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// ListAllFormFields returns all the incident form fields, both built-in and custom.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllFormFields(ctx context.Context) ([]FormField, error) {
+	logger := ctxzap.Extract(ctx)
+	var formFields []FormField
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListFormFieldsAPIEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-form-fields: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp FormFieldsResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-form-fields: %w", err)
+		}
+		formFields = append(formFields, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return formFields, nil
+}
+
+// ListAllFormFieldOptions returns all the options of a select form field.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllFormFieldOptions(ctx context.Context, formFieldID string) ([]FormFieldOption, error) {
+	logger := ctxzap.Extract(ctx)
+	if formFieldID == "" {
+		logger.Error("list-all-form-field-options: formFieldID is required")
+		return nil, fmt.Errorf("list-all-form-field-options: formFieldID is required")
+	}
+	var options []FormFieldOption
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListFormFieldOptionsAPIEndpoint, formFieldID)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-form-field-options: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp FormFieldOptionsResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-form-field-options: %w", err)
+		}
+		options = append(options, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return options, nil
+}
+
+// ListAllSchedules returns all the schedules.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllSchedules(ctx context.Context) ([]Schedule, error) {
+	var schedules []Schedule
+	var currentPage string
+	for {
+		page, nextPage, err := c.GetSchedules(ctx, currentPage)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-schedules: %w", err)
+		}
+		schedules = append(schedules, page...)
+
+		currentPage = nextPage
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return schedules, nil
+}
+
+// UpdateScheduleOwner makes a user the owner of a schedule.
+func (c *Client) UpdateScheduleOwner(ctx context.Context, scheduleID string, ownerUserID int) (*Schedule, error) {
+	logger := ctxzap.Extract(ctx)
+	if scheduleID == "" {
+		logger.Error("update-schedule-owner: scheduleID is required")
+		return nil, fmt.Errorf("update-schedule-owner: scheduleID is required")
+	}
+	parsedURL := c.generateURL(UpdateScheduleAPIEndpoint, nil, scheduleID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req ScheduleOwnerRequest
+	req.Data.Type = "schedules"
+	req.Data.Attributes.OwnerUserID = ownerUserID
+	var resp ScheduleResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPut,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update-schedule-owner: %w", err)
+	}
+	return &resp.Data, nil
+}
+
+// ListAllServices returns all the services, along with their owners.
+func (c *Client) ListAllServices(ctx context.Context) ([]OwnedObject, error) {
+	services, err := c.listAllOwnedObjects(ctx, ListServicesAPIEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("list-all-services: %w", err)
+	}
+	return services, nil
+}
+
+// UpdateServiceOwners replaces the users owning a service.
+func (c *Client) UpdateServiceOwners(ctx context.Context, serviceID string, ownerUserIDs []int) (*OwnedObject, error) {
+	service, err := c.updateOwners(ctx, UpdateServiceAPIEndpoint, "services", serviceID, ownerUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("update-service-owners: %w", err)
+	}
+	return service, nil
+}
+
+// ListAllFunctionalities returns all the functionalities, along with their owners.
+func (c *Client) ListAllFunctionalities(ctx context.Context) ([]OwnedObject, error) {
+	functionalities, err := c.listAllOwnedObjects(ctx, ListFunctionalitiesAPIEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("list-all-functionalities: %w", err)
+	}
+	return functionalities, nil
+}
+
+// UpdateFunctionalityOwners replaces the users owning a functionality.
+func (c *Client) UpdateFunctionalityOwners(
+	ctx context.Context,
+	functionalityID string,
+	ownerUserIDs []int,
+) (*OwnedObject, error) {
+	functionality, err := c.updateOwners(ctx, UpdateFunctionalityAPIEndpoint, "functionalities", functionalityID, ownerUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("update-functionality-owners: %w", err)
+	}
+	return functionality, nil
+}
+
+// ListAllEscalationPolicies returns all the escalation policies, along with their owners.
+func (c *Client) ListAllEscalationPolicies(ctx context.Context) ([]OwnedObject, error) {
+	policies, err := c.listAllOwnedObjects(ctx, ListEscalationPoliciesAPIEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("list-all-escalation-policies: %w", err)
+	}
+	return policies, nil
+}
+
+// UpdateEscalationPolicyOwners replaces the users owning an escalation policy.
+func (c *Client) UpdateEscalationPolicyOwners(
+	ctx context.Context,
+	escalationPolicyID string,
+	ownerUserIDs []int,
+) (*OwnedObject, error) {
+	policy, err := c.updateOwners(ctx, UpdateEscalationPolicyAPIEndpoint, "escalation_policies", escalationPolicyID, ownerUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("update-escalation-policy-owners: %w", err)
+	}
+	return policy, nil
+}
+
+// listAllOwnedObjects returns all the objects listed by an endpoint of objects owned by a list of users.
+func (c *Client) listAllOwnedObjects(ctx context.Context, endpoint string) ([]OwnedObject, error) {
+	logger := ctxzap.Extract(ctx)
+	var objects []OwnedObject
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp OwnedObjectsResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return objects, nil
+}
+
+// updateOwners replaces the users owning an object, given the endpoint and JSON:API type of the object.
+func (c *Client) updateOwners(
+	ctx context.Context,
+	endpoint string,
+	dataType string,
+	objectID string,
+	ownerUserIDs []int,
+) (*OwnedObject, error) {
+	logger := ctxzap.Extract(ctx)
+	if objectID == "" {
+		return nil, fmt.Errorf("the ID of the %s is required", dataType)
+	}
+	parsedURL := c.generateURL(endpoint, nil, objectID)
+	logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+	var req OwnersRequest
+	req.Data.Type = dataType
+	// an empty list, rather than null, removes all the owners
+	req.Data.Attributes.OwnersUserIDs = append([]int{}, ownerUserIDs...)
+	var resp OwnedObjectResponse
+	err := c.doRequest(
+		ctx,
+		http.MethodPut,
+		parsedURL,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// ListAllAlertUrgencies returns all the alert urgencies configured in Rootly.
+// It uses pagination under the hood to make one or more requests to build the full list.
+func (c *Client) ListAllAlertUrgencies(ctx context.Context) ([]AlertUrgency, error) {
+	logger := ctxzap.Extract(ctx)
+	var urgencies []AlertUrgency
+	var currentPage string
+	for {
+		parsedURL, err := c.generateCurrentPaginatedURL(ctx, currentPage, ListAlertUrgenciesAPIEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-alert-urgencies: %w", err)
+		}
+		logger.Debug("Generated URL", zap.String("parsedURL", parsedURL.String()))
+
+		var resp AlertUrgenciesResponse
+		err = c.doRequest(
+			ctx,
+			http.MethodGet,
+			parsedURL,
+			nil,
+			&resp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list-all-alert-urgencies: %w", err)
+		}
+		urgencies = append(urgencies, resp.Data...)
+
+		currentPage = resp.Links.Next
+		if currentPage == "" {
+			break
+		}
+	}
+
+	return urgencies, nil
+}
@@ -2,10 +2,13 @@ package client
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/stretchr/testify/require"
@@ -355,6 +358,105 @@ const (
         "total_count": 2,
         "total_pages": 2
     }
+}`
+	scheduleRotationsWithUsersListResultsPage1of1Size3 = `{
+    "data": [
+        {
+            "id": "test-weekday-rotation-guid",
+            "type": "schedule_rotations",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "name": "rotation weekdays"
+            },
+            "relationships": {
+                "schedule_rotation_users": {
+                    "data": [
+                        {
+                            "id": "test-rotation-user-guid-1",
+                            "type": "schedule_rotation_users"
+                        },
+                        {
+                            "id": "test-rotation-user-guid-2",
+                            "type": "schedule_rotation_users"
+                        }
+                    ]
+                }
+            }
+        },
+        {
+            "id": "test-weekend-rotation-guid",
+            "type": "schedule_rotations",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "name": "rotation weekends"
+            },
+            "relationships": {
+                "schedule_rotation_users": {
+                    "data": [
+                        {
+                            "id": "test-rotation-user-guid-3",
+                            "type": "schedule_rotation_users"
+                        },
+                        {
+                            "id": "test-rotation-user-guid-4",
+                            "type": "schedule_rotation_users"
+                        }
+                    ]
+                }
+            }
+        },
+        {
+            "id": "test-holiday-rotation-guid",
+            "type": "schedule_rotations",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "name": "rotation holidays"
+            }
+        }
+    ],
+    "included": [
+        {
+            "id": "test-rotation-user-guid-1",
+            "type": "schedule_rotation_users",
+            "attributes": {
+                "schedule_rotation_id": "test-weekday-rotation-guid",
+                "user_id": 96913,
+                "position": 1
+            }
+        },
+        {
+            "id": "test-rotation-user-guid-2",
+            "type": "schedule_rotation_users",
+            "attributes": {
+                "schedule_rotation_id": "test-weekday-rotation-guid",
+                "user_id": 97487,
+                "position": 2
+            }
+        },
+        {
+            "id": "test-rotation-user-guid-3",
+            "type": "schedule_rotation_users",
+            "attributes": {
+                "schedule_rotation_id": "test-weekend-rotation-guid",
+                "user_id": 96913,
+                "position": 1
+            }
+        }
+    ],
+    "links": {
+        "self": "https://api.example.com/v1/schedules/test-schedule-guid/schedule_rotations?include=schedule_rotation_users&page%5Bnumber%5D=1&page%5Bsize%5D=3",
+        "first": "https://api.example.com/v1/schedules/test-schedule-guid/schedule_rotations?include=schedule_rotation_users&page%5Bnumber%5D=1&page%5Bsize%5D=3",
+        "prev": null,
+        "next": null,
+        "last": "https://api.example.com/v1/schedules/test-schedule-guid/schedule_rotations?include=schedule_rotation_users&page%5Bnumber%5D=1&page%5Bsize%5D=3"
+    },
+    "meta": {
+        "current_page": 1,
+        "next_page": null,
+        "prev_page": null,
+        "total_count": 3,
+        "total_pages": 1
+    }
 }`
 	scheduleRotationUsersListResultsPage1of2Size1 = `{
     "data": [
@@ -396,6 +498,14 @@ const (
                 "starts_at": "2025-04-09T12:00:00.000-07:00",
                 "ends_at": "2025-04-11T23:59:59.000-07:00",
                 "is_override": true
+            },
+            "relationships": {
+                "user": {
+                    "data": {
+                        "id": "97487",
+                        "type": "users"
+                    }
+                }
             }
         }
     ],
@@ -417,6 +527,89 @@ const (
             }
         }
     ]
+}`
+	scheduleShiftsListResultsPage1of2Size2 = `{
+    "data": [
+        {
+            "id": "test-shift-guid-1",
+            "type": "shifts",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "starts_at": "2025-04-09T12:00:00.000-07:00",
+                "ends_at": "2025-04-10T12:00:00.000-07:00",
+                "is_override": false
+            },
+            "relationships": {
+                "user": {
+                    "data": {
+                        "id": "97487",
+                        "type": "users"
+                    }
+                }
+            }
+        },
+        {
+            "id": "test-shift-guid-2",
+            "type": "shifts",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "starts_at": "2025-04-10T12:00:00.000-07:00",
+                "ends_at": "2025-04-11T12:00:00.000-07:00",
+                "is_override": false
+            },
+            "relationships": {
+                "user": {
+                    "data": {
+                        "id": "96913",
+                        "type": "users"
+                    }
+                }
+            }
+        }
+    ],
+    "links": {
+        "next": "https://api.example.com/v1/shifts?page%5Bnumber%5D=2&page%5Bsize%5D=2"
+    }
+}`
+	scheduleShiftsListResultsPage2of2Size2 = `{
+    "data": [
+        {
+            "id": "test-shift-guid-3",
+            "type": "shifts",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "starts_at": "2025-04-11T12:00:00.000-07:00",
+                "ends_at": "2025-04-12T12:00:00.000-07:00",
+                "is_override": true
+            },
+            "relationships": {
+                "user": {
+                    "data": {
+                        "id": "97487",
+                        "type": "users"
+                    }
+                }
+            }
+        },
+        {
+            "id": "test-shift-guid-4",
+            "type": "shifts",
+            "attributes": {
+                "schedule_id": "test-schedule-guid",
+                "starts_at": "2025-04-12T12:00:00.000-07:00",
+                "ends_at": "2025-04-13T12:00:00.000-07:00",
+                "is_override": false
+            },
+            "relationships": {
+                "user": {
+                    "data": null
+                }
+            }
+        }
+    ],
+    "links": {
+        "next": null
+    }
 }`
 )
 
@@ -640,6 +833,39 @@ func TestClient_GetTeams(t *testing.T) {
 	require.Equal(t, expectedNextToken, nextPageToken)
 }
 
+func TestClient_GetTeams_WithName(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				require.Equal(t, "Team1", request.URL.Query().Get("filter[name]"))
+				require.Equal(t, "1", request.URL.Query().Get("page[number]"))
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte(teamsListResultsPage1of4Size1))
+				if err != nil {
+					return
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(
+		ctx,
+		server.URL,
+		testAPIKey,
+		testPageSize,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	teams, _, err := client.GetTeams(ctx, "", WithName("Team1")) // empty page token
+	require.Nil(t, err)
+	require.Len(t, teams, testPageSize)
+}
+
 func TestClient_GetTeamMemberAndAdminIDs(t *testing.T) {
 	teamID := "sre-team-guid"
 	expectedMemberIDs := []int{96913, 97487}
@@ -676,6 +902,164 @@ func TestClient_GetTeamMemberAndAdminIDs(t *testing.T) {
 	require.ElementsMatch(t, expectedAdminIDs, adminIDs)
 }
 
+func TestClient_GetUser(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				if request.URL.Path != "/v1/users/97487" {
+					writer.WriteHeader(http.StatusNotFound)
+					_, _ = writer.Write([]byte(`{"errors": [{"title": "Not found", "status": "404"}]}`))
+					return
+				}
+				writer.WriteHeader(http.StatusOK)
+				_, _ = writer.Write([]byte(`{
+    "data": {
+        "id": "97487",
+        "type": "users",
+        "attributes": {"name": "Jane Doe", "email": "jane@example.com"}
+    }
+}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	user, err := client.GetUser(ctx, "97487")
+	require.NoError(t, err)
+	require.Equal(t, "97487", user.ID)
+	require.Equal(t, "jane@example.com", user.Attributes.Email)
+
+	_, err = client.GetUser(ctx, "96913")
+	require.True(t, IsNotFound(err))
+
+	_, err = client.GetUser(ctx, "")
+	require.Error(t, err)
+}
+
+func TestClient_ListAllUserContactsConcurrently(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				switch request.URL.Path {
+				case "/v1/users/96913/phone_numbers":
+					_, _ = writer.Write([]byte(`{"data": [
+						{"id": "1", "type": "user_phone_numbers", "attributes": {"phone": "+15550100", "verified_at": "2023-01-01T00:00:00Z"}},
+						{"id": "2", "type": "user_phone_numbers", "attributes": {"phone": "+15550101", "verified_at": null}}
+					], "links": {"next": null}}`))
+				case "/v1/users/96913/email_addresses":
+					_, _ = writer.Write([]byte(`{"data": [
+						{"id": "3", "type": "user_email_addresses", "attributes": {"email": "jane@example.com", "verified_at": "2023-01-01T00:00:00Z"}}
+					], "links": {"next": null}}`))
+				case "/v1/users/96913/notification_rules":
+					_, _ = writer.Write([]byte(`{"data": [
+						{"id": "4", "type": "user_notification_rules"},
+						{"id": "5", "type": "user_notification_rules"}
+					], "links": {"next": null}}`))
+				case "/v1/users/97487/phone_numbers", "/v1/users/97487/email_addresses", "/v1/users/97487/notification_rules":
+					_, _ = writer.Write([]byte(`{"data": [], "links": {"next": null}}`))
+				default:
+					// e.g. a user deleted during the sync
+					writer.WriteHeader(http.StatusNotFound)
+					_, _ = writer.Write([]byte(`{"errors": [{"title": "Not found", "status": "404"}]}`))
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize, WithConcurrency(2))
+	require.NoError(t, err)
+
+	contacts, err := client.ListAllUserContactsConcurrently(ctx, []string{"96913", "97487", "98000"})
+	require.NoError(t, err)
+	require.Equal(t, []UserContacts{
+		{
+			UserID:                 "96913",
+			VerifiedPhoneNumbers:   1,
+			VerifiedEmailAddresses: 1,
+			NotificationRules:      2,
+			Complete:               true,
+		},
+		{
+			UserID:   "97487",
+			Complete: true,
+		},
+		{
+			UserID: "98000",
+		},
+	}, contacts)
+	require.True(t, contacts[0].Pageable())
+	require.False(t, contacts[1].Pageable())
+}
+
+func TestClient_GetImage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	cdn := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the API key isn't leaked to hosts other than the Rootly API
+		require.Empty(t, request.Header.Get("Authorization"))
+		switch request.URL.Path {
+		case "/avatar":
+			writer.Header().Set(uhttp.ContentType, "application/octet-stream")
+			_, _ = writer.Write(png)
+		case "/page":
+			writer.Header().Set(uhttp.ContentType, "text/html; charset=utf-8")
+			_, _ = writer.Write([]byte("<html></html>"))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cdn.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, "/api/") {
+			require.Equal(t, "Bearer "+testAPIKey, request.Header.Get("Authorization"))
+		} else {
+			// the API key isn't leaked to paths of the same host outside the base URL
+			require.Empty(t, request.Header.Get("Authorization"))
+		}
+		writer.Header().Set(uhttp.ContentType, "image/jpeg")
+		_, _ = writer.Write([]byte("jpeg"))
+	}))
+	defer api.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, api.URL+"/api", testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	contentType, body, err := client.GetImage(ctx, cdn.URL+"/avatar")
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "image/png", contentType)
+	require.Equal(t, png, content)
+
+	contentType, body, err = client.GetImage(ctx, "/api/uploads/avatar.jpg")
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "image/jpeg", contentType)
+
+	for _, imageURL := range []string{"/uploads/avatar.jpg", "/apiary/avatar.jpg", api.URL + "/api/../uploads/avatar.jpg"} {
+		contentType, body, err = client.GetImage(ctx, imageURL)
+		require.NoError(t, err)
+		require.NoError(t, body.Close())
+		require.Equal(t, "image/jpeg", contentType)
+	}
+
+	_, _, err = client.GetImage(ctx, cdn.URL+"/page")
+	require.ErrorContains(t, err, "isn't an image")
+	_, _, err = client.GetImage(ctx, cdn.URL+"/unknown")
+	require.True(t, IsNotFound(err))
+	_, _, err = client.GetImage(ctx, "file:///etc/passwd")
+	require.Error(t, err)
+}
+
 func TestClient_GetSecrets(t *testing.T) {
 	expectedSecrets := []Secret{
 		{
@@ -772,6 +1156,43 @@ func TestClient_GetSchedules(t *testing.T) {
 	require.Equal(t, expectedNextToken, nextPageToken)
 }
 
+func TestClient_Uncached(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				requests++
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				writer.WriteHeader(http.StatusOK)
+				_, _ = writer.Write([]byte(schedulesListResultsPage1of2Size1))
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	// the second request is served from the GET cache
+	for range 2 {
+		_, _, err = client.GetSchedules(ctx, "")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, requests)
+
+	// while the uncached client requests the API each time, leaving the client it's derived from cached
+	for range 2 {
+		schedules, _, err := client.Uncached().GetSchedules(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, schedules, testPageSize)
+	}
+	require.Equal(t, 3, requests)
+	_, _, err = client.GetSchedules(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 3, requests)
+}
+
 func TestClient_ListScheduleRotations(t *testing.T) {
 	testScheduleID := "test-schedule-guid"
 	expectedRotationIDs := []string{"test-weekday-rotation-guid"}
@@ -810,6 +1231,58 @@ func TestClient_ListScheduleRotations(t *testing.T) {
 	require.Equal(t, expectedNextToken, nextPageToken)
 }
 
+func TestClient_ListScheduleRotationsWithUsers(t *testing.T) {
+	testScheduleID := "test-schedule-guid"
+	expectedRotations := []ScheduleRotationMembers{
+		{
+			RotationID: "test-weekday-rotation-guid",
+			UserIDs:    []int{96913, 97487},
+			Complete:   true,
+		},
+		{
+			// one of the rotation users is missing from the included section
+			RotationID: "test-weekend-rotation-guid",
+			Complete:   false,
+		},
+		{
+			// the relationship linkage is missing
+			RotationID: "test-holiday-rotation-guid",
+			Complete:   false,
+		},
+	}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				require.Equal(t, "/v1/schedules/"+testScheduleID+"/schedule_rotations", request.URL.Path)
+				require.Equal(t, "schedule_rotation_users", request.URL.Query().Get("include"))
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte(scheduleRotationsWithUsersListResultsPage1of1Size3))
+				if err != nil {
+					return
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(
+		ctx,
+		server.URL,
+		testAPIKey,
+		3,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotations, nextPageToken, err := client.ListScheduleRotationsWithUsers(ctx, testScheduleID, "") // empty page token
+	require.Nil(t, err)
+	require.Equal(t, expectedRotations, rotations)
+	require.Empty(t, nextPageToken)
+}
+
 func TestClient_ListScheduleRotationUsers(t *testing.T) {
 	testRotationID := "test-weekday-rotation-guid"
 	expectedUserIDs := []int{96913}
@@ -876,11 +1349,88 @@ func TestClient_ListOnCallUsers(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	userIDs, err := client.ListOnCallUsers(ctx, testScheduleID)
+	userIDs, err := client.ListOnCallUsers(ctx, testScheduleID, DefaultOnCallWindow)
 	require.Nil(t, err)
 	require.ElementsMatch(t, expectedUserIDs, userIDs)
 }
 
+func TestClient_ListAllOnCallShifts(t *testing.T) {
+	testScheduleID := "test-schedule-guid"
+	expectedShifts := []OnCallShift{
+		{
+			ShiftID:    "test-shift-guid-1",
+			ScheduleID: testScheduleID,
+			UserID:     97487,
+			StartsAt:   "2025-04-09T12:00:00.000-07:00",
+			EndsAt:     "2025-04-10T12:00:00.000-07:00",
+		},
+		{
+			ShiftID:    "test-shift-guid-2",
+			ScheduleID: testScheduleID,
+			UserID:     96913,
+			StartsAt:   "2025-04-10T12:00:00.000-07:00",
+			EndsAt:     "2025-04-11T12:00:00.000-07:00",
+		},
+		{
+			ShiftID:    "test-shift-guid-3",
+			ScheduleID: testScheduleID,
+			UserID:     97487,
+			StartsAt:   "2025-04-11T12:00:00.000-07:00",
+			EndsAt:     "2025-04-12T12:00:00.000-07:00",
+			IsOverride: true,
+		},
+		// the fourth shift has no user, so it's skipped
+	}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				require.Equal(t, "/v1/shifts", request.URL.Path)
+				body := scheduleShiftsListResultsPage1of2Size2
+				if request.URL.Query().Get("page[number]") == "2" {
+					body = scheduleShiftsListResultsPage2of2Size2
+				} else {
+					// the first request sets the window, which the next page link carries over
+					from, err := time.Parse(time.RFC3339, request.URL.Query().Get("from"))
+					require.Nil(t, err)
+					to, err := time.Parse(time.RFC3339, request.URL.Query().Get("to"))
+					require.Nil(t, err)
+					require.Equal(t, 24*time.Hour, to.Sub(from))
+					require.Equal(t, testScheduleID, request.URL.Query().Get("schedule_ids[]"))
+				}
+				// point the next page link at the test server
+				body = strings.ReplaceAll(body, testBaseURLStr, "http://"+request.Host)
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte(body))
+				if err != nil {
+					return
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(
+		ctx,
+		server.URL,
+		testAPIKey,
+		2,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shifts, err := client.ListAllOnCallShifts(ctx, testScheduleID, 24*time.Hour)
+	require.Nil(t, err)
+	require.Equal(t, expectedShifts, shifts)
+
+	// on-call users are de-duplicated across shifts
+	userIDs, err := client.ListOnCallUsers(ctx, testScheduleID, 24*time.Hour)
+	require.Nil(t, err)
+	require.Equal(t, []int{97487, 96913}, userIDs)
+}
+
 func TestClient_generateCurrentPaginatedURL(t *testing.T) {
 	ctx := context.Background()
 	client, err := NewClient(ctx, testBaseURLStr, testAPIKey, 6)
@@ -1108,3 +1658,70 @@ func TestClient_generateURL(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ListAudits(t *testing.T) {
+	createdAtOrAfter := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				require.Equal(t, "/v1/audits", request.URL.Path)
+				query := request.URL.Query()
+				require.Equal(t, "2025-04-10T12:00:00Z", query.Get("filter[created_at][gte]"))
+				require.NotEmpty(t, query.Get("filter[created_at][lte]"))
+				require.Equal(t, "created_at", query.Get("sort"))
+				require.Equal(t, "50", query.Get("page[size]"))
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte(`{
+    "data": [
+        {
+            "id": "test-audit-guid-1",
+            "type": "audits",
+            "attributes": {
+                "item_type": "User",
+                "item_id": 97487,
+                "event": "update",
+                "user_id": 96913,
+                "object_changes": {"name": ["Sam", "Sam Testsalot"]},
+                "created_at": "2025-04-10T12:00:01.123Z"
+            }
+        },
+        {
+            "id": "test-audit-guid-2",
+            "type": "audits",
+            "attributes": {
+                "item_type": "Group",
+                "item_id": "sre-team-guid",
+                "event": "create",
+                "user_id": null,
+                "object_changes": null,
+                "created_at": "2025-04-10T12:00:02.000Z"
+            }
+        }
+    ],
+    "links": {
+        "next": null
+    }
+}`))
+				if err != nil {
+					return
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	audits, nextPageToken, err := client.ListAudits(ctx, createdAtOrAfter, 50, "")
+	require.NoError(t, err)
+	require.Empty(t, nextPageToken)
+	require.Len(t, audits, 2)
+	require.Equal(t, FlexibleID("97487"), audits[0].Attributes.ItemID)
+	require.Equal(t, 96913, *audits[0].Attributes.UserID)
+	require.Len(t, audits[0].Attributes.ObjectChanges["name"], 2)
+	require.Equal(t, FlexibleID("sre-team-guid"), audits[1].Attributes.ItemID)
+	require.Nil(t, audits[1].Attributes.UserID)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is how many requests the client sends at once when fetching the details of several resources.
+const DefaultConcurrency = 4
+
+// WithConcurrency sets how many requests the client sends at once when fetching the details of several resources,
+// e.g. the users of several schedule rotations. All the requests share the rate limit throttle, so they're spread out
+// when the rate limit budget runs low. Non-positive values fall back to DefaultConcurrency.
+func WithConcurrency(workers int) ClientOption {
+	return func(c *Client) {
+		if workers <= 0 {
+			workers = DefaultConcurrency
+		}
+		c.concurrency = workers
+	}
+}
+
+// forEachConcurrently calls fn for each index from 0 to n-1, with at most c.concurrency calls in flight.
+// It stops at the first error, cancelling the context of the calls in flight, and returns that error.
+func (c *Client) forEachConcurrently(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for range min(max(c.concurrency, 1), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
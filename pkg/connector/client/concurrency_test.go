@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// newLatencyServer returns a fake Rootly API server answering the schedule rotation users and team endpoints after
+// the given latency, and a function returning the number of requests received and the most requests in flight at
+// once. Resources whose ID starts with "deleted" respond 404.
+func newLatencyServer(tb testing.TB, latency time.Duration) (*httptest.Server, func() (int64, int64)) {
+	tb.Helper()
+	var requests, inFlight, maxInFlight atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests.Add(1)
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(latency)
+
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+		if len(segments) < 3 || strings.HasPrefix(segments[2], "deleted") {
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = writer.Write([]byte(`{"errors": [{"title": "Not found", "status": "404"}]}`))
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+		switch segments[1] {
+		case "schedule_rotations":
+			_, _ = fmt.Fprintf(writer, `{
+    "data": [
+        {"id": "%[1]s-user", "type": "schedule_rotation_users", "attributes": {"user_id": %[2]d}}
+    ],
+    "links": {"next": null}
+}`, segments[2], len(segments[2]))
+		case "teams":
+			_, _ = writer.Write([]byte(teamGetResult))
+		}
+	}))
+	tb.Cleanup(server.Close)
+	return server, func() (int64, int64) {
+		return requests.Load(), maxInFlight.Load()
+	}
+}
+
+func TestClient_ListAllScheduleRotationUsersConcurrently(t *testing.T) {
+	server, stats := newLatencyServer(t, 20*time.Millisecond)
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize, WithConcurrency(2))
+	require.NoError(t, err)
+
+	rotations, err := client.ListAllScheduleRotationUsersConcurrently(ctx, []string{"r1", "rotation-2", "deleted", "r-4"})
+	require.NoError(t, err)
+	require.Equal(t, []ScheduleRotationMembers{
+		{RotationID: "r1", UserIDs: []int{2}, Complete: true},
+		{RotationID: "rotation-2", UserIDs: []int{10}, Complete: true},
+		{RotationID: "deleted", Deleted: true},
+		{RotationID: "r-4", UserIDs: []int{3}, Complete: true},
+	}, rotations)
+
+	requests, maxInFlight := stats()
+	require.Equal(t, int64(4), requests)
+	require.Equal(t, int64(2), maxInFlight)
+}
+
+func TestClient_GetTeamsMemberAndAdminIDs(t *testing.T) {
+	server, stats := newLatencyServer(t, 0)
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	teams, err := client.GetTeamsMemberAndAdminIDs(ctx, []string{"sre-team-guid", "deleted-team-guid"})
+	require.NoError(t, err)
+	require.Equal(t, []TeamMembers{
+		{TeamID: "sre-team-guid", UserIDs: []int{96913, 97487}, AdminIDs: []int{96913}},
+		{TeamID: "deleted-team-guid", Deleted: true},
+	}, teams)
+
+	requests, _ := stats()
+	require.Equal(t, int64(2), requests)
+}
+
+func TestClient_forEachConcurrently(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(ctx, testBaseURLStr, testAPIKey, testPageSize, WithConcurrency(3))
+	require.NoError(t, err)
+
+	t.Run("bounded concurrency", func(t *testing.T) {
+		var mu sync.Mutex
+		var inFlight, maxInFlight int
+		called := make([]bool, 10)
+		err := client.forEachConcurrently(ctx, len(called), func(_ context.Context, i int) error {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			called[i] = true
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, maxInFlight)
+		require.NotContains(t, called, false)
+	})
+
+	t.Run("stops at the first error", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var calls atomic.Int64
+		err := client.forEachConcurrently(ctx, 100, func(ctx context.Context, i int) error {
+			calls.Add(1)
+			if i == 0 {
+				return errFailed
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		require.ErrorIs(t, err, errFailed)
+		require.Less(t, calls.Load(), int64(100))
+	})
+
+	t.Run("nothing to do", func(t *testing.T) {
+		err := client.forEachConcurrently(ctx, 0, func(context.Context, int) error {
+			t.Fatal("unexpected call")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+// BenchmarkClient_ListAllScheduleRotationUsersConcurrently measures how long fetching the users of 32 rotations
+// takes against a server with a 10ms latency, depending on the number of workers.
+func BenchmarkClient_ListAllScheduleRotationUsersConcurrently(b *testing.B) {
+	// use distinct rotation IDs on each iteration, so that responses aren't served from the client's GET cache
+	rotationIDs := func(iteration int) []string {
+		ids := make([]string, 32)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("rotation-%d-%d", iteration, i)
+		}
+		return ids
+	}
+	for _, workers := range []int{1, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			server, stats := newLatencyServer(b, 10*time.Millisecond)
+			ctx := context.Background()
+			client, err := NewClient(ctx, server.URL, testAPIKey, testPageSize, WithConcurrency(workers))
+			require.NoError(b, err)
+
+			b.ResetTimer()
+			for i := range b.N {
+				_, err := client.ListAllScheduleRotationUsersConcurrently(ctx, rotationIDs(i))
+				require.NoError(b, err)
+			}
+			b.StopTimer()
+
+			requests, maxInFlight := stats()
+			b.ReportMetric(float64(requests)/float64(b.N), "requests/op")
+			b.ReportMetric(float64(maxInFlight), "max-in-flight")
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is an error response from the Rootly API. It keeps the HTTP status code and each JSON:API error,
+// and converts to the gRPC status matching the HTTP status code.
+type APIError struct {
+	StatusCode int
+	Errors     []RootlyError
+	// RateLimit is the rate limit budget reported along with the error, if any.
+	RateLimit *v2.RateLimitDescription
+}
+
+// newAPIError returns the error for a Rootly API response with an error status code.
+func newAPIError(statusCode int, errorResponse RootlyErrorResponse, rateLimit *v2.RateLimitDescription) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Errors:     errorResponse.Errors,
+	}
+	if rateLimit.GetLimit() != 0 || rateLimit.GetStatus() == v2.RateLimitDescription_STATUS_OVERLIMIT {
+		apiErr.RateLimit = rateLimit
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	errorResponse := RootlyErrorResponse{Errors: e.Errors}
+	return fmt.Sprintf("rootly API request failed with status %d: %s", e.StatusCode, errorResponse.Message())
+}
+
+// Code returns the gRPC code matching the HTTP status code of the response. A 429 maps to Unavailable like a server
+// error, so check StatusCode to tell them apart.
+func (e *APIError) Code() codes.Code {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError {
+		// a rate limited request can be retried once the rate limit resets, as the SDK does for Unavailable
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+// GRPCStatus lets status.Code and status.FromError convert the error, with the rate limit as a detail when known.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Error())
+	if e.RateLimit != nil {
+		if withDetails, err := st.WithDetails(e.RateLimit); err == nil {
+			return withDetails
+		}
+	}
+	return st
+}
+
+// IsNotFound reports whether the error is a Rootly API response saying the requested resource doesn't exist,
+// e.g. because it was deleted during the sync.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsForbidden reports whether the error is a Rootly API response saying the API key isn't allowed to access the
+// requested resource.
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
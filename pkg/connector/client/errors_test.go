@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClient_APIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantCode   codes.Code
+		wantErrors []RootlyError
+		wantMsg    string
+	}{
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"errors": [{"title": "Record not found", "status": "404"}]}`,
+			wantCode:   codes.NotFound,
+			wantErrors: []RootlyError{{Title: "Record not found", Status: "404"}},
+			wantMsg:    "rootly API request failed with status 404: Record not found: 404",
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"errors": [{"title": "Forbidden", "status": "403", "code": "insufficient_scope"}]}`,
+			wantCode:   codes.PermissionDenied,
+			wantErrors: []RootlyError{{Title: "Forbidden", Status: "403", Code: "insufficient_scope"}},
+			wantMsg:    "rootly API request failed with status 403: Forbidden: 403, code: insufficient_scope",
+		},
+		{
+			name:       "validation failed",
+			statusCode: http.StatusUnprocessableEntity,
+			body: `{"errors": [
+				{"title": "is invalid", "status": "422", "source": {"pointer": "/data/attributes/starts_at"}},
+				{"title": "is not allowed", "status": "422", "source": {"parameter": "filter[foo]"}}
+			]}`,
+			wantCode: codes.InvalidArgument,
+			wantErrors: []RootlyError{
+				{Title: "is invalid", Status: "422", Source: RootlyErrorSource{Pointer: "/data/attributes/starts_at"}},
+				{Title: "is not allowed", Status: "422", Source: RootlyErrorSource{Parameter: "filter[foo]"}},
+			},
+			wantMsg: "rootly API request failed with status 422: " +
+				"is invalid: 422, source: /data/attributes/starts_at; is not allowed: 422, parameter: filter[foo]",
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"errors": [{"title": "Too many requests", "status": "429"}]}`,
+			wantCode:   codes.Unavailable,
+			wantErrors: []RootlyError{{Title: "Too many requests", Status: "429"}},
+			wantMsg:    "rootly API request failed with status 429: Too many requests: 429",
+		},
+		{
+			name:       "bad gateway without a JSON body",
+			statusCode: http.StatusBadGateway,
+			wantCode:   codes.Unavailable,
+			wantMsg:    "rootly API request failed with status 502: Unknown error from Rootly API",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if tc.body != "" {
+					writer.Header().Set(uhttp.ContentType, "application/json")
+				}
+				writer.WriteHeader(tc.statusCode)
+				_, _ = writer.Write([]byte(tc.body))
+			}))
+			t.Cleanup(server.Close)
+
+			ctx := context.Background()
+			c, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+			require.NoError(t, err)
+			// a 429 throttles the next request, which isn't under test here
+			c.throttle.sleep = func(_ context.Context, _ time.Duration) error { return nil }
+
+			_, _, err = c.GetSchedules(ctx, "")
+			require.Error(t, err)
+			require.Equal(t, tc.wantCode, status.Code(err))
+			require.Equal(t, tc.statusCode == http.StatusNotFound, IsNotFound(err))
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			require.Equal(t, tc.statusCode, apiErr.StatusCode)
+			require.Equal(t, tc.wantErrors, apiErr.Errors)
+			require.Equal(t, tc.wantMsg, apiErr.Error())
+		})
+	}
+}
+
+func TestAPIError_GRPCStatus(t *testing.T) {
+	rateLimit := &v2.RateLimitDescription{
+		Status: v2.RateLimitDescription_STATUS_OVERLIMIT,
+		Limit:  3000,
+	}
+	err := fmt.Errorf("get-schedules: %w", &APIError{
+		StatusCode: http.StatusTooManyRequests,
+		RateLimit:  rateLimit,
+	})
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unavailable, st.Code())
+	require.Len(t, st.Details(), 1)
+	require.Equal(t, int64(3000), st.Details()[0].(*v2.RateLimitDescription).Limit)
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -22,10 +24,17 @@ type Meta struct {
 }
 
 type RootlyError struct {
-	Title  string `json:"title"`
-	Status string `json:"status"`
-	Code   string `json:"code"`   // optional
-	Detail string `json:"detail"` // optional
+	Title  string            `json:"title"`
+	Status string            `json:"status"`
+	Code   string            `json:"code"`   // optional
+	Detail string            `json:"detail"` // optional
+	Source RootlyErrorSource `json:"source"` // optional
+}
+
+// RootlyErrorSource points to the part of the request that caused an error, per the JSON:API spec.
+type RootlyErrorSource struct {
+	Pointer   string `json:"pointer"`   // e.g. /data/attributes/name
+	Parameter string `json:"parameter"` // e.g. filter[name]
 }
 
 // RootlyError represents an error response from the Rootly API.
@@ -47,6 +56,12 @@ func (e *RootlyErrorResponse) Message() string {
 		if rootlyError.Detail != "" {
 			msg += fmt.Sprintf(", detail: %s", rootlyError.Detail)
 		}
+		if rootlyError.Source.Pointer != "" {
+			msg += fmt.Sprintf(", source: %s", rootlyError.Source.Pointer)
+		}
+		if rootlyError.Source.Parameter != "" {
+			msg += fmt.Sprintf(", parameter: %s", rootlyError.Source.Parameter)
+		}
 		msgs = append(msgs, msg)
 	}
 	return strings.Join(msgs, "; ")
@@ -60,6 +75,16 @@ type UserAttributes struct {
 	SlackID   string `json:"slack_id"`
 	UpdatedAt string `json:"updated_at"`
 	CreatedAt string `json:"created_at"`
+	// AvatarURL is the URL of the profile picture of the user, if any.
+	AvatarURL string `json:"avatar_url"`
+
+	// InvitationSentAt is when the user was invited to Rootly, if they were, and InvitationAcceptedAt when they
+	// accepted the invitation, if they did.
+	InvitationSentAt     string `json:"invitation_sent_at"`
+	InvitationAcceptedAt string `json:"invitation_accepted_at"`
+	// CurrentSignInAt is when the user last signed in, and LastSignInAt when they signed in before that.
+	CurrentSignInAt string `json:"current_sign_in_at"`
+	LastSignInAt    string `json:"last_sign_in_at"`
 }
 
 type User struct {
@@ -68,6 +93,71 @@ type User struct {
 	Attributes UserAttributes `json:"attributes"`
 }
 
+type UserResponse struct {
+	Data User `json:"data"`
+}
+
+type UserPhoneNumberAttributes struct {
+	Phone string `json:"phone"`
+	// VerifiedAt is when the user verified the phone number, if they did.
+	VerifiedAt string `json:"verified_at"`
+}
+
+type UserPhoneNumber struct {
+	ID         string                    `json:"id"`
+	Type       string                    `json:"type"`
+	Attributes UserPhoneNumberAttributes `json:"attributes"`
+}
+
+type UserPhoneNumbersResponse struct {
+	Data  []UserPhoneNumber `json:"data"`
+	Links Links             `json:"links"`
+	Meta  Meta              `json:"meta"`
+}
+
+type UserEmailAddressAttributes struct {
+	Email string `json:"email"`
+	// VerifiedAt is when the user verified the email address, if they did.
+	VerifiedAt string `json:"verified_at"`
+}
+
+type UserEmailAddress struct {
+	ID         string                     `json:"id"`
+	Type       string                     `json:"type"`
+	Attributes UserEmailAddressAttributes `json:"attributes"`
+}
+
+type UserEmailAddressesResponse struct {
+	Data  []UserEmailAddress `json:"data"`
+	Links Links              `json:"links"`
+	Meta  Meta               `json:"meta"`
+}
+
+type UserNotificationRulesResponse struct {
+	// note the rules are only counted, so their attributes aren't needed
+	Data  []ObjectWithoutAttributes `json:"data"`
+	Links Links                     `json:"links"`
+	Meta  Meta                      `json:"meta"`
+}
+
+// UserContacts summarizes how Rootly can page a user: the contact methods they verified, and their notification
+// rules, which page them through those contact methods.
+type UserContacts struct {
+	UserID                 string
+	VerifiedPhoneNumbers   int
+	VerifiedEmailAddresses int
+	NotificationRules      int
+	// Complete is false when the contact methods of the user couldn't be read, e.g. because the user was deleted
+	// meanwhile or the API key isn't allowed to, in which case the counts are meaningless.
+	Complete bool
+}
+
+// Pageable reports whether Rootly can page the user, i.e. they have a notification rule and a verified phone number
+// to call or text. A verified email address alone only lets Rootly notify them, which isn't enough to page them.
+func (c *UserContacts) Pageable() bool {
+	return c.NotificationRules > 0 && c.VerifiedPhoneNumbers > 0
+}
+
 type UsersResponse struct {
 	Data  []User `json:"data"`
 	Links Links  `json:"links"`
@@ -86,6 +176,8 @@ type TeamAttributes struct {
 	AdminIDs    []int  `json:"admin_ids"`
 	UpdatedAt   string `json:"updated_at"`
 	CreatedAt   string `json:"created_at"`
+	// IconURL is the URL of the icon of the team, if any.
+	IconURL string `json:"icon_url"`
 }
 
 type Team struct {
@@ -104,6 +196,15 @@ type TeamResponse struct {
 	Data Team `json:"data"`
 }
 
+// TeamMembers holds the member and admin user IDs of a team.
+type TeamMembers struct {
+	TeamID   string
+	UserIDs  []int
+	AdminIDs []int
+	// Deleted is true when the team no longer existed by the time its members were fetched.
+	Deleted bool
+}
+
 type SecretAttributes struct {
 	Name      string `json:"name"`
 	UpdatedAt string `json:"updated_at"`
@@ -116,6 +217,10 @@ type Secret struct {
 	Attributes SecretAttributes `json:"attributes"`
 }
 
+type SecretResponse struct {
+	Data Secret `json:"data"`
+}
+
 type SecretsResponse struct {
 	Data  []Secret `json:"data"`
 	Links Links    `json:"links"`
@@ -148,9 +253,48 @@ type ScheduleResponse struct {
 }
 
 type ScheduleRotationsResponse struct {
-	Data  []ObjectWithoutAttributes `json:"data"`
-	Links Links                     `json:"links"`
-	Meta  Meta                      `json:"meta"`
+	Data  []ScheduleRotation `json:"data"`
+	Links Links              `json:"links"`
+	Meta  Meta               `json:"meta"`
+}
+
+type Relationship struct {
+	// note data is null when Rootly doesn't provide the linkage of a relationship
+	Data []ObjectWithoutAttributes `json:"data"`
+}
+
+type ScheduleRotationRelationships struct {
+	ScheduleRotationUsers Relationship `json:"schedule_rotation_users"`
+}
+
+type ScheduleRotationAttributes struct {
+	UpdatedAt string `json:"updated_at"`
+	// note there are more attributes available but don't need them
+}
+
+type ScheduleRotation struct {
+	ID            string                        `json:"id"`
+	Type          string                        `json:"type"`
+	Attributes    ScheduleRotationAttributes    `json:"attributes"`
+	Relationships ScheduleRotationRelationships `json:"relationships"`
+}
+
+type ScheduleRotationsWithUsersResponse struct {
+	Data     []ScheduleRotation     `json:"data"`
+	Included []ScheduleRotationUser `json:"included"`
+	Links    Links                  `json:"links"`
+	Meta     Meta                   `json:"meta"`
+}
+
+// ScheduleRotationMembers holds the member user IDs of a schedule rotation.
+type ScheduleRotationMembers struct {
+	RotationID string
+	UserIDs    []int
+	// Complete is false when the included rotation users were truncated or missing,
+	// in which case the members need to be listed separately.
+	Complete bool
+	// Deleted is true when the rotation no longer existed by the time its members were listed.
+	Deleted bool
 }
 
 type ScheduleRotationUserAttributes struct {
@@ -176,7 +320,440 @@ type ObjectWithoutAttributes struct {
 	// note there's an attributes object available but don't need or want it
 }
 
+type SingleRelationship struct {
+	// note data is null when Rootly doesn't provide the linkage of a relationship
+	Data *ObjectWithoutAttributes `json:"data"`
+}
+
+type ScheduleShiftAttributes struct {
+	ScheduleID string `json:"schedule_id"`
+	StartsAt   string `json:"starts_at"`
+	EndsAt     string `json:"ends_at"`
+	IsOverride bool   `json:"is_override"`
+}
+
+type ScheduleShiftRelationships struct {
+	User SingleRelationship `json:"user"`
+}
+
+type ScheduleShift struct {
+	ID            string                     `json:"id"`
+	Type          string                     `json:"type"`
+	Attributes    ScheduleShiftAttributes    `json:"attributes"`
+	Relationships ScheduleShiftRelationships `json:"relationships"`
+}
+
 type ScheduleShiftsResponse struct {
-	// note there's a data object available but don't need it
+	Data []ScheduleShift `json:"data"`
+	// note the included users have attributes available but don't need them
 	Included []ObjectWithoutAttributes `json:"included"`
+	Links    Links                     `json:"links"`
+	Meta     Meta                      `json:"meta"`
+}
+
+// OnCallShift is a shift during which a user is on-call for a schedule.
+type OnCallShift struct {
+	ShiftID    string
+	ScheduleID string
+	UserID     int
+	StartsAt   string
+	EndsAt     string
+	IsOverride bool
+}
+
+type OverrideShiftAttributes struct {
+	ScheduleID string `json:"schedule_id"`
+	UserID     int    `json:"user_id"`
+	StartsAt   string `json:"starts_at"`
+	EndsAt     string `json:"ends_at"`
+}
+
+type OverrideShift struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Attributes OverrideShiftAttributes `json:"attributes"`
+}
+
+type OverrideShiftResponse struct {
+	Data OverrideShift `json:"data"`
+}
+
+type OverrideShiftRequestAttributes struct {
+	UserID   int    `json:"user_id"`
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+}
+
+type OverrideShiftRequest struct {
+	Data struct {
+		Type       string                         `json:"type"`
+		Attributes OverrideShiftRequestAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type AlertAttributes struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	// Status is e.g. triggered until a paged user acknowledges the alert, then acknowledged, or resolved.
+	Status                 string `json:"status"`
+	NotificationTargetType string `json:"notification_target_type"`
+	NotificationTargetID   string `json:"notification_target_id"`
+	AlertUrgencyID         string `json:"alert_urgency_id"`
+	UpdatedAt              string `json:"updated_at"`
+	CreatedAt              string `json:"created_at"`
+}
+
+type Alert struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Attributes AlertAttributes `json:"attributes"`
+}
+
+type AlertResponse struct {
+	Data Alert `json:"data"`
+}
+
+type AlertRequestAttributes struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"`
+	// NotificationTargetType is the kind of target paged, i.e. Schedule, EscalationPolicy, Group or User.
+	NotificationTargetType string `json:"notification_target_type"`
+	NotificationTargetID   string `json:"notification_target_id"`
+	AlertUrgencyID         string `json:"alert_urgency_id,omitempty"`
+}
+
+type AlertRequest struct {
+	Data struct {
+		Type       string                 `json:"type"`
+		Attributes AlertRequestAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type AlertUrgencyAttributes struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type AlertUrgency struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Attributes AlertUrgencyAttributes `json:"attributes"`
+}
+
+type AlertUrgenciesResponse struct {
+	Data  []AlertUrgency `json:"data"`
+	Links Links          `json:"links"`
+	Meta  Meta           `json:"meta"`
+}
+
+type ScheduleOwnerRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			OwnerUserID int `json:"owner_user_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// OwnedAttributes are the attributes shared by the Rootly objects owned by a list of users, i.e. services,
+// functionalities and escalation policies.
+type OwnedAttributes struct {
+	Name          string `json:"name"`
+	OwnersUserIDs []int  `json:"owners_user_ids"`
+}
+
+type OwnedObject struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Attributes OwnedAttributes `json:"attributes"`
+}
+
+type OwnedObjectResponse struct {
+	Data OwnedObject `json:"data"`
+}
+
+type OwnedObjectsResponse struct {
+	Data  []OwnedObject `json:"data"`
+	Links Links         `json:"links"`
+	Meta  Meta          `json:"meta"`
+}
+
+type OwnersRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			OwnersUserIDs []int `json:"owners_user_ids"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// FlexibleID is a Rootly resource ID, which the API encodes either as a string, e.g. for teams and schedules, or as
+// a number, e.g. for users.
+type FlexibleID string
+
+// UnmarshalJSON accepts a JSON string, number, or null.
+func (id *FlexibleID) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		*id = ""
+	case string:
+		*id = FlexibleID(v)
+	case json.Number:
+		*id = FlexibleID(v.String())
+	default:
+		return fmt.Errorf("unexpected ID %s", string(data))
+	}
+	return nil
+}
+
+type AuditAttributes struct {
+	// ItemType is the kind of audited item, e.g. User, Group, Secret or Schedule.
+	ItemType string     `json:"item_type"`
+	ItemID   FlexibleID `json:"item_id"`
+	// Event is the audited action, i.e. create, update or destroy for changes, or e.g. view for accesses.
+	Event string `json:"event"`
+	// UserID is the user who performed the action, if any.
+	UserID *int `json:"user_id"`
+	// Object holds the attributes of the item as of the action.
+	Object map[string]json.RawMessage `json:"object"`
+	// ObjectChanges holds the [before, after] values of each changed attribute of the item.
+	ObjectChanges map[string][]json.RawMessage `json:"object_changes"`
+	CreatedAt     string                       `json:"created_at"`
+}
+
+type Audit struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Attributes AuditAttributes `json:"attributes"`
+}
+
+type AuditsResponse struct {
+	Data  []Audit `json:"data"`
+	Links Links   `json:"links"`
+	Meta  Meta    `json:"meta"`
+}
+
+type IncidentAttributes struct {
+	Title        string `json:"title"`
+	SequentialID int    `json:"sequential_id"`
+	Summary      string `json:"summary"`
+	// Status is e.g. in_triage, started, mitigated, resolved, closed or cancelled.
+	Status      string `json:"status"`
+	URL         string `json:"url"`
+	ResolvedAt  string `json:"resolved_at"`
+	ClosedAt    string `json:"closed_at"`
+	CancelledAt string `json:"cancelled_at"`
+	UpdatedAt   string `json:"updated_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type Incident struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Attributes IncidentAttributes `json:"attributes"`
+}
+
+type IncidentsResponse struct {
+	Data  []Incident `json:"data"`
+	Links Links      `json:"links"`
+	Meta  Meta       `json:"meta"`
+}
+
+type IncidentResponse struct {
+	Data Incident `json:"data"`
+}
+
+type IncidentRequestAttributes struct {
+	Title           string   `json:"title"`
+	Summary         string   `json:"summary,omitempty"`
+	Status          string   `json:"status,omitempty"`
+	SeverityID      string   `json:"severity_id,omitempty"`
+	IncidentTypeIDs []string `json:"incident_type_ids,omitempty"`
+}
+
+type IncidentRequest struct {
+	Data struct {
+		Type       string                    `json:"type"`
+		Attributes IncidentRequestAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type FormFieldSelectionRequestAttributes struct {
+	FormFieldID       string   `json:"form_field_id"`
+	Value             string   `json:"value,omitempty"`
+	SelectedOptionIDs []string `json:"selected_option_ids,omitempty"`
+}
+
+type FormFieldSelectionRequest struct {
+	Data struct {
+		Type       string                              `json:"type"`
+		Attributes FormFieldSelectionRequestAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type SeverityAttributes struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	// Severity is the level of the severity, e.g. critical, high, medium or low.
+	Severity string `json:"severity"`
+}
+
+type Severity struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Attributes SeverityAttributes `json:"attributes"`
+}
+
+type SeveritiesResponse struct {
+	Data  []Severity `json:"data"`
+	Links Links      `json:"links"`
+	Meta  Meta       `json:"meta"`
+}
+
+type IncidentTypeAttributes struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+}
+
+type IncidentType struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Attributes IncidentTypeAttributes `json:"attributes"`
+}
+
+type IncidentTypeResponse struct {
+	Data IncidentType `json:"data"`
+}
+
+type IncidentTypesResponse struct {
+	Data  []IncidentType `json:"data"`
+	Links Links          `json:"links"`
+	Meta  Meta           `json:"meta"`
+}
+
+type FormFieldAttributes struct {
+	// Kind is custom for the fields defined by the organization, or the incident attribute a built-in field sets.
+	Kind string `json:"kind"`
+	// InputKind is e.g. text, textarea, select, multi_select, number, date or datetime.
+	InputKind   string `json:"input_kind"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type FormField struct {
+	ID         string              `json:"id"`
+	Type       string              `json:"type"`
+	Attributes FormFieldAttributes `json:"attributes"`
+}
+
+type FormFieldsResponse struct {
+	Data  []FormField `json:"data"`
+	Links Links       `json:"links"`
+	Meta  Meta        `json:"meta"`
+}
+
+type FormFieldOptionAttributes struct {
+	Value    string `json:"value"`
+	Position int    `json:"position"`
+}
+
+type FormFieldOption struct {
+	ID         string                    `json:"id"`
+	Type       string                    `json:"type"`
+	Attributes FormFieldOptionAttributes `json:"attributes"`
+}
+
+type FormFieldOptionsResponse struct {
+	Data  []FormFieldOption `json:"data"`
+	Links Links             `json:"links"`
+	Meta  Meta              `json:"meta"`
+}
+
+type ActionItemAttributes struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	// Status is e.g. open, in_progress, cancelled or done.
+	Status     string `json:"status"`
+	Priority   string `json:"priority"`
+	IncidentID string `json:"incident_id"`
+	URL        string `json:"url"`
+	UpdatedAt  string `json:"updated_at"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type ActionItem struct {
+	ID         string               `json:"id"`
+	Type       string               `json:"type"`
+	Attributes ActionItemAttributes `json:"attributes"`
+}
+
+type ActionItemResponse struct {
+	Data ActionItem `json:"data"`
+}
+
+type ActionItemRequestAttributes struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind"`
+	Status      string `json:"status,omitempty"`
+}
+
+type ActionItemRequest struct {
+	Data struct {
+		Type       string                      `json:"type"`
+		Attributes ActionItemRequestAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type IncidentEventAttributes struct {
+	// Event describes what happened, e.g. a user joining the incident channel.
+	Event string `json:"event"`
+	// Kind is e.g. event for the entries added to the timeline by users, or trail for those recorded by Rootly.
+	Kind string `json:"kind"`
+	// UserID is the user the event is attributed to, if any.
+	UserID     *int   `json:"user_id"`
+	OccurredAt string `json:"occurred_at"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type IncidentEvent struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Attributes IncidentEventAttributes `json:"attributes"`
+}
+
+type IncidentEventsResponse struct {
+	Data  []IncidentEvent `json:"data"`
+	Links Links           `json:"links"`
+	Meta  Meta            `json:"meta"`
+}
+
+type IncidentRoleAssignmentAttributes struct {
+	IncidentRoleID FlexibleID `json:"incident_role_id"`
+	// UserID is the user assigned the role, if any.
+	UserID    *int   `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type IncidentRoleAssignment struct {
+	ID         string                           `json:"id"`
+	Type       string                           `json:"type"`
+	Attributes IncidentRoleAssignmentAttributes `json:"attributes"`
+}
+
+type IncidentRoleAssignmentsResponse struct {
+	Data  []IncidentRoleAssignment `json:"data"`
+	Links Links                    `json:"links"`
+	Meta  Meta                     `json:"meta"`
 }
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// parsePageToken parses a page token, ie the links.next URL of a previous response. Relative URLs are resolved
+// against the base URL, and URLs outside of the base URL are rejected with InvalidArgument, so that a tampered
+// token or next link never gets the API key sent to another host.
+func (c *Client) parsePageToken(pToken string) (*url.URL, error) {
+	parsedURL, err := url.Parse(pToken)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+	}
+
+	if parsedURL.Scheme == "" && parsedURL.Host == "" {
+		// a relative next link, e.g. /v1/users?page[number]=2
+		base := *c.baseURL
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+			base.RawPath = ""
+		}
+		parsedURL = base.ResolveReference(parsedURL)
+	}
+
+	if parsedURL.Scheme != c.baseURL.Scheme ||
+		!strings.EqualFold(parsedURL.Host, c.baseURL.Host) ||
+		parsedURL.User != nil ||
+		parsedURL.Opaque != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "page token %q doesn't match the base URL %s", pToken, c.baseURL)
+	}
+
+	// the path must stay under the base URL path, so dot segments aren't allowed either
+	for _, segment := range strings.Split(parsedURL.Path, "/") {
+		if segment == "." || segment == ".." {
+			return nil, status.Errorf(codes.InvalidArgument, "page token %q is outside of the base URL %s", pToken, c.baseURL)
+		}
+	}
+	basePath := strings.TrimSuffix(c.baseURL.Path, "/")
+	tokenPath := "/" + strings.TrimPrefix(parsedURL.Path, "/")
+	if basePath != "" && tokenPath != basePath && !strings.HasPrefix(tokenPath, basePath+"/") {
+		return nil, status.Errorf(codes.InvalidArgument, "page token %q is outside of the base URL %s", pToken, c.baseURL)
+	}
+
+	return parsedURL, nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// lowRateLimitRatio is the share of the rate limit below which requests are spread out over the time left until the
+// rate limit resets, rather than sent as fast as possible until Rootly answers 429.
+const lowRateLimitRatio = 0.1
+
+// rateLimitThrottle tracks the rate limit budget reported by the Rootly API response headers, and delays requests
+// when the budget runs low. It's shared by all the requests of a client, including concurrent ones.
+type rateLimitThrottle struct {
+	mu          sync.Mutex
+	description *v2.RateLimitDescription
+	// sent counts the requests sent since the description was recorded, which it doesn't account for yet
+	sent int64
+	// nextAt is the earliest time the next request may be sent while requests are spread out
+	nextAt time.Time
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func newRateLimitThrottle() *rateLimitThrottle {
+	return &rateLimitThrottle{
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+// sleepContext waits for the given duration, or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe records the rate limit description extracted from a response, ignoring responses without rate limit
+// headers.
+func (t *rateLimitThrottle) observe(description *v2.RateLimitDescription) {
+	if description.GetLimit() == 0 && description.GetStatus() != v2.RateLimitDescription_STATUS_OVERLIMIT {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.description = description
+	t.sent = 0
+}
+
+// latest returns a copy of the last recorded rate limit description, or nil if none was recorded.
+func (t *rateLimitThrottle) latest() *v2.RateLimitDescription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.description == nil {
+		return nil
+	}
+	return proto.Clone(t.description).(*v2.RateLimitDescription)
+}
+
+// delay returns how long to wait before the next request: until the reset when the budget is exhausted, or the time
+// left until the reset spread over the remaining budget when it's low.
+func (t *rateLimitThrottle) delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, _ := t.pace(t.now())
+	return d
+}
+
+// pace returns how long to wait before the next request, and how far apart requests are spread out after it, given
+// the budget left once the requests sent since the description was recorded are accounted for.
+func (t *rateLimitThrottle) pace(now time.Time) (time.Duration, time.Duration) {
+	if t.description == nil || t.description.GetResetAt() == nil {
+		return 0, 0
+	}
+	untilReset := t.description.GetResetAt().AsTime().Sub(now)
+	if untilReset <= 0 {
+		return 0, 0
+	}
+	remaining := t.description.GetRemaining() - t.sent
+	if t.description.GetStatus() == v2.RateLimitDescription_STATUS_OVERLIMIT || remaining <= 0 {
+		return untilReset, 0
+	}
+	if float64(remaining) >= float64(t.description.GetLimit())*lowRateLimitRatio {
+		return 0, 0
+	}
+	interval := untilReset / time.Duration(remaining+1)
+	return interval, interval
+}
+
+// reserve returns how long to wait before sending a request, and counts it against the budget. Concurrent requests
+// reserve successive slots, so they're spread out rather than all sent at once after the same delay.
+func (t *rateLimitThrottle) reserve() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	d, interval := t.pace(now)
+	sendAt := now.Add(d)
+	if interval > 0 {
+		if sendAt.Before(t.nextAt) {
+			sendAt = t.nextAt
+		}
+		// the budget is replenished at the reset, so there's no need to spread requests out any further
+		if resetAt := t.description.GetResetAt().AsTime(); sendAt.After(resetAt) {
+			sendAt = resetAt
+		}
+		t.nextAt = sendAt.Add(interval)
+	}
+	t.sent++
+	return sendAt.Sub(now)
+}
+
+// wait delays the next request according to the rate limit budget.
+func (t *rateLimitThrottle) wait(ctx context.Context) error {
+	d := t.reserve()
+	if d <= 0 {
+		return nil
+	}
+	ctxzap.Extract(ctx).Debug("Throttling request to stay within the Rootly API rate limit", zap.Duration("delay", d))
+	return t.sleep(ctx, d)
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestClient_RateLimitThrottle(t *testing.T) {
+	type response struct {
+		statusCode int
+		headers    map[string]string
+	}
+	var mu sync.Mutex
+	var responses []response
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		resp := responses[0]
+		responses = responses[1:]
+		mu.Unlock()
+
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		for key, value := range resp.headers {
+			writer.Header().Set(key, value)
+		}
+		writer.WriteHeader(resp.statusCode)
+		if resp.statusCode == http.StatusTooManyRequests {
+			_, _ = writer.Write([]byte(`{"errors": [{"title": "Rate limit exceeded", "status": "429"}]}`))
+			return
+		}
+		_, _ = writer.Write([]byte(`{"data": [], "links": {"next": null}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+	var delays []time.Duration
+	c.throttle.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	require.Nil(t, c.RateLimit())
+
+	// rate limited
+	responses = append(responses, response{
+		statusCode: http.StatusTooManyRequests,
+		headers:    map[string]string{"Retry-After": "30"},
+	})
+	_, _, err = c.GetUsers(ctx, "")
+	require.Error(t, err)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, v2.RateLimitDescription_STATUS_OVERLIMIT, c.RateLimit().Status)
+
+	// the next request waits for the rate limit to reset
+	responses = append(responses, response{
+		statusCode: http.StatusOK,
+		headers: map[string]string{
+			"X-RateLimit-Limit":     "100",
+			"X-RateLimit-Remaining": "5",
+			"X-RateLimit-Reset":     "60",
+		},
+	})
+	_, _, err = c.GetUsers(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, delays, 1)
+	require.InDelta(t, 30*time.Second, delays[0], float64(2*time.Second))
+	require.Equal(t, int64(100), c.RateLimit().Limit)
+	require.Equal(t, int64(5), c.RateLimit().Remaining)
+
+	// after a response with a low remaining budget, requests are spread until the reset
+	responses = append(responses, response{
+		statusCode: http.StatusOK,
+		headers: map[string]string{
+			"X-RateLimit-Limit":     "100",
+			"X-RateLimit-Remaining": "50",
+			"X-RateLimit-Reset":     "60",
+		},
+	})
+	_, _, err = c.GetTeams(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, delays, 2)
+	require.InDelta(t, 10*time.Second, delays[1], float64(time.Second))
+
+	// after a response with enough budget left, requests aren't delayed
+	responses = append(responses, response{statusCode: http.StatusOK})
+	_, _, err = c.GetSchedules(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, delays, 2)
+}
+
+func Test_rateLimitThrottle_delay(t *testing.T) {
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		description *v2.RateLimitDescription
+		want        time.Duration
+	}{
+		{
+			name: "no rate limit headers seen",
+			want: 0,
+		},
+		{
+			name: "over limit",
+			description: &v2.RateLimitDescription{
+				Status:  v2.RateLimitDescription_STATUS_OVERLIMIT,
+				Limit:   1,
+				ResetAt: timestamppb.New(now.Add(20 * time.Second)),
+			},
+			want: 20 * time.Second,
+		},
+		{
+			name: "over limit, already reset",
+			description: &v2.RateLimitDescription{
+				Status:  v2.RateLimitDescription_STATUS_OVERLIMIT,
+				Limit:   1,
+				ResetAt: timestamppb.New(now.Add(-time.Second)),
+			},
+			want: 0,
+		},
+		{
+			name: "low budget",
+			description: &v2.RateLimitDescription{
+				Status:    v2.RateLimitDescription_STATUS_OK,
+				Limit:     1000,
+				Remaining: 9,
+				ResetAt:   timestamppb.New(now.Add(30 * time.Second)),
+			},
+			want: 3 * time.Second,
+		},
+		{
+			name: "enough budget",
+			description: &v2.RateLimitDescription{
+				Status:    v2.RateLimitDescription_STATUS_OK,
+				Limit:     1000,
+				Remaining: 100,
+				ResetAt:   timestamppb.New(now.Add(30 * time.Second)),
+			},
+			want: 0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			throttle := newRateLimitThrottle()
+			throttle.now = func() time.Time { return now }
+			if tc.description != nil {
+				throttle.observe(tc.description)
+			}
+			require.Equal(t, tc.want, throttle.delay())
+		})
+	}
+}
+
+func Test_rateLimitThrottle_reserve(t *testing.T) {
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	throttle := newRateLimitThrottle()
+	throttle.now = func() time.Time { return now }
+
+	// enough budget, requests aren't delayed
+	throttle.observe(&v2.RateLimitDescription{
+		Status:    v2.RateLimitDescription_STATUS_OK,
+		Limit:     100,
+		Remaining: 50,
+		ResetAt:   timestamppb.New(now.Add(60 * time.Second)),
+	})
+	require.Equal(t, time.Duration(0), throttle.reserve())
+	require.Equal(t, time.Duration(0), throttle.reserve())
+
+	// low budget, concurrent requests sent before any response get successive slots until the reset
+	throttle.observe(&v2.RateLimitDescription{
+		Status:    v2.RateLimitDescription_STATUS_OK,
+		Limit:     100,
+		Remaining: 5,
+		ResetAt:   timestamppb.New(now.Add(60 * time.Second)),
+	})
+	var delays []time.Duration
+	for range 6 {
+		delays = append(delays, throttle.reserve())
+	}
+	require.Equal(t, []time.Duration{
+		10 * time.Second,
+		20 * time.Second,
+		32 * time.Second,
+		47 * time.Second,
+		60 * time.Second,
+		60 * time.Second,
+	}, delays)
+}
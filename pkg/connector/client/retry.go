@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultRetryMaxAttempts = 4
+	DefaultRetryMaxElapsed  = 2 * time.Minute
+	retryBaseDelay          = 500 * time.Millisecond
+	retryMaxDelay           = 30 * time.Second
+)
+
+// ClientOption configures optional behavior of the client.
+type ClientOption func(*Client)
+
+// WithRetryPolicy retries GET requests failing with a transient error up to maxAttempts times in total, as long as
+// the retries fit in maxElapsed. Non-positive values fall back to DefaultRetryMaxAttempts and DefaultRetryMaxElapsed.
+func WithRetryPolicy(maxAttempts int, maxElapsed time.Duration) ClientOption {
+	return func(c *Client) {
+		if maxAttempts <= 0 {
+			maxAttempts = DefaultRetryMaxAttempts
+		}
+		if maxElapsed <= 0 {
+			maxElapsed = DefaultRetryMaxElapsed
+		}
+		c.retry.maxAttempts = maxAttempts
+		c.retry.maxElapsed = maxElapsed
+	}
+}
+
+// retryPolicy holds how transient errors from the Rootly API are retried.
+type retryPolicy struct {
+	maxAttempts int
+	maxElapsed  time.Duration
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+	jitter      func(d time.Duration) time.Duration
+}
+
+// newRetryPolicy returns a policy that doesn't retry, until configured by WithRetryPolicy.
+func newRetryPolicy() *retryPolicy {
+	return &retryPolicy{
+		maxAttempts: 1,
+		now:         time.Now,
+		sleep:       sleepContext,
+		jitter: func(d time.Duration) time.Duration {
+			return rand.N(d + 1) //nolint:gosec // backoff jitter doesn't need a secure random number
+		},
+	}
+}
+
+// backoff returns the delay before retrying after the given attempt, which grows exponentially with full jitter.
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	d := retryMaxDelay
+	if attempt < 16 {
+		d = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+	return p.jitter(d)
+}
+
+// isRetryable reports whether a request failed with a transient error worth retrying: a 429 or 5xx response, or a
+// reset connection. Errors from a cancelled context aren't retried.
+func isRetryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	// 429 and 5xx responses, and timeouts or resets while reading them, map to these codes
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failure is a fault injected by newFlakyServer: an HTTP status code, or 0 to reset the connection.
+type failure int
+
+const connectionReset failure = 0
+
+// newFlakyServer returns a fake Rootly API failing the first requests with the given failures, then succeeding,
+// and a function returning the number of requests received.
+func newFlakyServer(t *testing.T, failures ...failure) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		attempt := requests
+		requests++
+		mu.Unlock()
+
+		if attempt < len(failures) {
+			if failures[attempt] == connectionReset {
+				conn, _, err := writer.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.Close())
+				return
+			}
+			writer.Header().Set(uhttp.ContentType, "application/json")
+			writer.WriteHeader(int(failures[attempt]))
+			_, _ = writer.Write([]byte(`{"errors": [{"title": "Request failed", "status": "` + http.StatusText(int(failures[attempt])) + `"}]}`))
+			return
+		}
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		_, _ = writer.Write([]byte(usersListResultsPage1of2Size1))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []failure
+		maxAttempts  int
+		maxElapsed   time.Duration
+		wantCode     codes.Code
+		wantRequests int
+		wantDelays   []time.Duration
+	}{
+		{
+			name:         "no failures",
+			maxAttempts:  4,
+			wantRequests: 1,
+		},
+		{
+			name:         "bad gateway, unavailable and rate limited, then success",
+			failures:     []failure{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			maxAttempts:  4,
+			wantRequests: 4,
+			wantDelays:   []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second},
+		},
+		{
+			name:         "connection reset, then success",
+			failures:     []failure{connectionReset},
+			maxAttempts:  4,
+			wantRequests: 2,
+			wantDelays:   []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:         "max attempts reached",
+			failures:     []failure{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			maxAttempts:  2,
+			wantCode:     codes.Unavailable,
+			wantRequests: 2,
+			wantDelays:   []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:         "time budget exhausted",
+			failures:     []failure{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			maxAttempts:  10,
+			maxElapsed:   1200 * time.Millisecond,
+			wantCode:     codes.Unavailable,
+			wantRequests: 2,
+			wantDelays:   []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:         "not found isn't retried",
+			failures:     []failure{http.StatusNotFound},
+			maxAttempts:  4,
+			wantCode:     codes.NotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "forbidden isn't retried",
+			failures:     []failure{http.StatusForbidden},
+			maxAttempts:  4,
+			wantCode:     codes.PermissionDenied,
+			wantRequests: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requestCount := newFlakyServer(t, tc.failures...)
+			ctx := context.Background()
+			c, err := NewClient(ctx, server.URL, testAPIKey, testPageSize, WithRetryPolicy(tc.maxAttempts, tc.maxElapsed))
+			require.NoError(t, err)
+
+			// without jitter the delays are deterministic, and sleeping advances a fake clock
+			now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+			var delays []time.Duration
+			c.retry.now = func() time.Time { return now }
+			c.retry.jitter = func(d time.Duration) time.Duration { return d }
+			c.retry.sleep = func(_ context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				now = now.Add(d)
+				return nil
+			}
+			// a 429 without rate limit headers also throttles the next request, which isn't under test here
+			c.throttle.sleep = func(_ context.Context, _ time.Duration) error { return nil }
+
+			users, _, err := c.GetUsers(ctx, "")
+			if tc.wantCode != codes.OK {
+				require.Error(t, err)
+				require.Equal(t, tc.wantCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				require.Len(t, users, 1)
+			}
+			require.Equal(t, tc.wantRequests, requestCount())
+			require.Equal(t, tc.wantDelays, delays)
+		})
+	}
+}
+
+func TestClient_DoesNotRetryByDefault(t *testing.T) {
+	server, requestCount := newFlakyServer(t, http.StatusBadGateway)
+	ctx := context.Background()
+	c, err := NewClient(ctx, server.URL, testAPIKey, testPageSize)
+	require.NoError(t, err)
+
+	_, _, err = c.GetUsers(ctx, "")
+	require.Error(t, err)
+	require.Equal(t, 1, requestCount())
+}
+
+func Test_retryPolicy_backoff(t *testing.T) {
+	p := newRetryPolicy()
+	for attempt := 1; attempt < 100; attempt++ {
+		d := p.backoff(attempt)
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		require.LessOrEqual(t, d, retryMaxDelay)
+	}
+	p.jitter = func(d time.Duration) time.Duration { return d }
+	require.Equal(t, 500*time.Millisecond, p.backoff(1))
+	require.Equal(t, 4*time.Second, p.backoff(4))
+	require.Equal(t, retryMaxDelay, p.backoff(10))
+	require.Equal(t, retryMaxDelay, p.backoff(64))
+}
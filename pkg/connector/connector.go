@@ -4,31 +4,244 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	rootlyv1 "github.com/conductorone/baton-rootly/pb/rootly/v1"
 	"github.com/conductorone/baton-rootly/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Connector struct {
-	client *client.Client
+	client       *client.Client
+	baseURL      string
+	pageSize     int
+	incremental  *incrementalSync
+	onCallWindow time.Duration
+
+	retryMaxAttempts int
+	retryMaxElapsed  time.Duration
+	concurrency      int
+	syncUserContacts bool
+
+	skipResourceTypes []string
+	skipEntitlements  []string
+	nameFilters       map[string]namePatterns
+	selection         *syncSelection
+
+	ticketFields *ticketFieldsCache
+
+	probeOnce  sync.Once
+	probeErr   error
+	unreadable []string
+}
+
+// namePatterns holds the configured include and exclude regular expressions filtering resources by name.
+type namePatterns struct {
+	include string
+	exclude string
+}
+
+// Option configures optional behavior of the connector.
+type Option func(*Connector)
+
+// WithBaseURL sets the base URL of the Rootly API, e.g. for a regional endpoint or a local mock.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Connector) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithPageSize sets the number of resources requested per page from the Rootly API.
+func WithPageSize(pageSize int) Option {
+	return func(c *Connector) {
+		c.pageSize = pageSize
+	}
+}
+
+// WithOnCallWindow sets how far ahead of now schedule shifts are considered for on-call grants.
+func WithOnCallWindow(window time.Duration) Option {
+	return func(c *Connector) {
+		c.onCallWindow = window
+	}
+}
+
+// WithIncrementalSync enables reusing schedule membership grants from the previous sync for schedules that haven't
+// changed since, and forces them to be fetched again once they're older than fullSyncInterval.
+func WithIncrementalSync(fullSyncInterval time.Duration) Option {
+	return func(c *Connector) {
+		c.incremental = newIncrementalSync(true, fullSyncInterval)
+	}
+}
+
+// WithRetryPolicy sets how many times in total, and for how long, requests to the Rootly API failing with a
+// transient error are attempted.
+func WithRetryPolicy(maxAttempts int, maxElapsed time.Duration) Option {
+	return func(c *Connector) {
+		c.retryMaxAttempts = maxAttempts
+		c.retryMaxElapsed = maxElapsed
+	}
+}
+
+// WithConcurrency sets how many requests are sent to the Rootly API at once when fetching the members of several
+// schedule rotations or teams, or the contact methods of several users.
+func WithConcurrency(workers int) Option {
+	return func(c *Connector) {
+		c.concurrency = workers
+	}
+}
+
+// WithUserContactsSync enables fetching the verified contact methods and notification rules of each user into their
+// profile. It's off by default since it takes a few more requests per user.
+func WithUserContactsSync() Option {
+	return func(c *Connector) {
+		c.syncUserContacts = true
+	}
+}
+
+// WithSkippedResourceTypes disables syncing the resource types with the given IDs, e.g. "secret".
+func WithSkippedResourceTypes(resourceTypeIDs ...string) Option {
+	return func(c *Connector) {
+		c.skipResourceTypes = append(c.skipResourceTypes, resourceTypeIDs...)
+	}
+}
+
+// WithSkippedEntitlements disables syncing the given entitlements and their grants, each formatted as
+// "<resource type ID>:<entitlement name>", e.g. "schedule:on-call".
+func WithSkippedEntitlements(entitlements ...string) Option {
+	return func(c *Connector) {
+		c.skipEntitlements = append(c.skipEntitlements, entitlements...)
+	}
+}
+
+// WithTeamNameFilter only syncs the teams whose name matches the include regular expression, if not empty, and
+// doesn't match the exclude regular expression, if not empty.
+func WithTeamNameFilter(include string, exclude string) Option {
+	return func(c *Connector) {
+		c.nameFilters[teamResourceType.Id] = namePatterns{include: include, exclude: exclude}
+	}
+}
+
+// WithScheduleNameFilter only syncs the schedules whose name matches the include regular expression, if not empty,
+// and doesn't match the exclude regular expression, if not empty.
+func WithScheduleNameFilter(include string, exclude string) Option {
+	return func(c *Connector) {
+		c.nameFilters[scheduleResourceType.Id] = namePatterns{include: include, exclude: exclude}
+	}
+}
+
+// withRateLimit adds the rate limit budget last reported by the Rootly API, if any, to the annotations of a response.
+func withRateLimit(annos annotations.Annotations, c *client.Client) annotations.Annotations {
+	if rateLimit := c.RateLimit(); rateLimit != nil {
+		annos.Update(rateLimit)
+	}
+	return annos
+}
+
+// withWarning logs a warning and adds it to the annotations of a response, so that API callers see it too.
+func withWarning(ctx context.Context, annos annotations.Annotations, warning *rootlyv1.Warning) annotations.Annotations {
+	ctxzap.Extract(ctx).Warn(
+		warning.GetMessage(),
+		zap.String("resource_type_id", warning.GetResourceTypeId()),
+		zap.String("resource_id", warning.GetResourceId()),
+	)
+	annos.Append(warning)
+	return annos
+}
+
+// deletedResourceGrants ends the grants of a team or schedule deleted after it was listed, with a warning rather than
+// failing the sync.
+func deletedResourceGrants(
+	ctx context.Context,
+	c *client.Client,
+	resource *v2.Resource,
+	err error,
+) ([]*v2.Grant, string, annotations.Annotations, error) {
+	annos := withWarning(ctx, nil, &rootlyv1.Warning{
+		Message:        fmt.Sprintf("%s %s was deleted during the sync, its grants were skipped: %s", resource.Id.ResourceType, resource.Id.Resource, err),
+		ResourceTypeId: resource.Id.ResourceType,
+		ResourceId:     resource.Id.Resource,
+	})
+	return nil, "", withRateLimit(annos, c), nil
+}
+
+// EventFeeds returns the event feeds of the changes and accesses recorded by Rootly.
+func (d *Connector) EventFeeds(_ context.Context) []connectorbuilder.EventFeed {
+	feeds := []connectorbuilder.EventFeed{
+		newAuditFeed(d.client, d.selection),
+		newIncidentFeed(d.client),
+	}
+	if d.selection.syncsEntitlement(scheduleResourceType.Id, scheduleOnCallEntitlement) {
+		feeds = append(feeds, newOnCallFeed(d.client, d.onCallWindow, d.selection))
+	}
+	return feeds
+}
+
+// RegisterActionManager returns the manager of the custom actions run against Rootly, e.g. creating override shifts.
+func (d *Connector) RegisterActionManager(_ context.Context) (connectorbuilder.CustomActionManager, error) {
+	return newActionManager(d.client), nil
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
-func (d *Connector) ResourceSyncers(_ context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newTeamBuilder(d.client),
+func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
+	// an error is returned by Validate instead
+	_ = d.probeResourceTypes(ctx)
+
+	var syncers []connectorbuilder.ResourceSyncer
+	for _, syncer := range []connectorbuilder.ResourceSyncer{
+		newUserBuilder(d.client, d.syncUserContacts),
+		newTeamBuilder(d.client, d.selection),
 		newSecretBuilder(d.client),
-		newScheduleBuilder(d.client),
+		newScheduleBuilder(d.client, d.incremental, d.onCallWindow, d.selection),
+	} {
+		resourceTypeID := syncer.ResourceType(ctx).Id
+		if d.selection.syncsResourceType(resourceTypeID) && !slices.Contains(d.unreadable, resourceTypeID) {
+			syncers = append(syncers, syncer)
+		}
 	}
+	return syncers
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
 // It streams a response, always starting with a metadata object, following by chunked payloads for the asset.
-func (d *Connector) Asset(_ context.Context, _ *v2.AssetRef) (string, io.ReadCloser, error) {
-	return "", nil, nil
+// The assets are the avatars of users and the icons of teams, referenced as "<resource type>:<resource ID>", whose
+// image URL is looked up when the asset is fetched rather than kept in the reference.
+func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCloser, error) {
+	resourceTypeID, resourceID, _ := strings.Cut(asset.GetId(), ":")
+	var imageURL string
+	switch resourceTypeID {
+	case userResourceType.Id:
+		user, err := d.client.GetUser(ctx, resourceID)
+		if err != nil {
+			return "", nil, err
+		}
+		imageURL = user.Attributes.AvatarURL
+	case teamResourceType.Id:
+		team, err := d.client.GetTeam(ctx, resourceID)
+		if err != nil {
+			return "", nil, err
+		}
+		imageURL = team.Attributes.IconURL
+	default:
+		return "", nil, status.Errorf(codes.NotFound, "unknown asset %q", asset.GetId())
+	}
+	if imageURL == "" {
+		return "", nil, status.Errorf(codes.NotFound, "%s %s has no image anymore", resourceTypeID, resourceID)
+	}
+	return d.client.GetImage(ctx, imageURL)
+}
+
+// assetRef returns the reference to the image of a resource served by Asset.
+func assetRef(resourceTypeID string, resourceID string) *v2.AssetRef {
+	return &v2.AssetRef{Id: fmt.Sprintf("%s:%s", resourceTypeID, resourceID)}
 }
 
 // Metadata returns metadata about the connector.
@@ -39,29 +252,141 @@ func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 	}, nil
 }
 
+// resourceTypeProbes maps each resource type ID to the list endpoint probed before syncing, and whether reading it is
+// required. Users are required since they're the principal of every grant, while the other resource types are left
+// out of the sync when they can't be read.
+var resourceTypeProbes = []struct {
+	resourceTypeID string
+	endpoint       string
+	required       bool
+}{
+	{userResourceType.Id, client.ListUsersAPIEndpoint, true},
+	{teamResourceType.Id, client.ListTeamsAPIEndpoint, false},
+	{secretResourceType.Id, client.ListSecretsAPIEndpoint, false},
+	{scheduleResourceType.Id, client.ListSchedulesAPIEndpoint, false},
+}
+
+// probeResourceTypes probes the list endpoint of each synced resource type once, recording the optional ones the
+// API key can't read so that ResourceSyncers leaves them out, and returns an error when the API key is invalid or
+// can't read a required resource type.
+func (d *Connector) probeResourceTypes(ctx context.Context) error {
+	d.probeOnce.Do(func() {
+		if d.client.IsTest() {
+			// skip for capabilities and config generation
+			return
+		}
+		logger := ctxzap.Extract(ctx)
+		for _, probe := range resourceTypeProbes {
+			if !d.selection.syncsResourceType(probe.resourceTypeID) {
+				continue
+			}
+			err := d.client.ProbeList(ctx, probe.endpoint)
+			switch status.Code(err) {
+			case codes.OK:
+				continue
+			case codes.Unauthenticated:
+				// the API key itself is invalid, so no other endpoint will be readable either
+				d.probeErr = fmt.Errorf("rootly client validation failed, the API key is invalid: %w", err)
+				return
+			case codes.PermissionDenied:
+			default:
+				d.probeErr = fmt.Errorf("rootly client validation failed for %s: %w", probe.resourceTypeID, err)
+				return
+			}
+
+			if probe.required {
+				d.probeErr = fmt.Errorf("rootly client validation failed, the API key can't read %s: %w", probe.resourceTypeID, err)
+				return
+			}
+			logger.Warn(
+				"The API key can't read an optional resource type, so it is left out of the sync",
+				zap.String("resource_type", probe.resourceTypeID),
+				zap.Error(err),
+			)
+			d.unreadable = append(d.unreadable, probe.resourceTypeID)
+		}
+	})
+	return d.probeErr
+}
+
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+// It probes the list endpoint of each synced resource type, failing when a required one can't be read and leaving
+// optional ones out of the sync, and returns annotations listing the resource types and entitlements that are synced.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	if d.client.IsTest() {
-		// skip for capabilities and config generation
-		return nil, nil
+	if err := d.probeResourceTypes(ctx); err != nil {
+		return nil, err
 	}
 
-	_, _, err := d.client.GetUsers(ctx, "")
-	if err != nil {
-		return nil, fmt.Errorf("rootly client validation failed: %w", err)
+	// the resource types and entitlements that are synced, i.e. the selected ones the API key can read
+	resourceTypeIDs, entitlementIDs := d.selection.effective()
+	resourceTypes := &v2.ResourceTypesServiceListResourceTypesResponse{}
+	for _, resourceType := range []*v2.ResourceType{userResourceType, teamResourceType, secretResourceType, scheduleResourceType} {
+		if slices.Contains(resourceTypeIDs, resourceType.Id) && !slices.Contains(d.unreadable, resourceType.Id) {
+			resourceTypes.List = append(resourceTypes.List, resourceType)
+		}
+	}
+	entitlements := &v2.EntitlementsServiceListEntitlementsResponse{}
+	for _, entitlementID := range entitlementIDs {
+		resourceTypeID, name, _ := strings.Cut(entitlementID, ":")
+		if slices.Contains(d.unreadable, resourceTypeID) {
+			continue
+		}
+		entitlements.List = append(entitlements.List, &v2.Entitlement{
+			Id:          entitlementID,
+			DisplayName: name,
+			Slug:        name,
+			Resource:    &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeID}},
+		})
 	}
 
-	return nil, nil
+	var annos annotations.Annotations
+	annos.Update(resourceTypes)
+	annos.Update(entitlements)
+	return annos, nil
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, apiKey string) (*Connector, error) {
-	rootlyClient, err := client.NewClient(ctx, client.BaseURLStr, apiKey, client.ResourcesPageSize)
+func New(ctx context.Context, apiKey string, opts ...Option) (*Connector, error) {
+	c := &Connector{
+		baseURL:      client.BaseURLStr,
+		pageSize:     client.ResourcesPageSize,
+		incremental:  newIncrementalSync(false, defaultFullSyncInterval),
+		onCallWindow: client.DefaultOnCallWindow,
+		nameFilters:  make(map[string]namePatterns),
+		ticketFields: &ticketFieldsCache{},
+
+		retryMaxAttempts: client.DefaultRetryMaxAttempts,
+		retryMaxElapsed:  client.DefaultRetryMaxElapsed,
+		concurrency:      client.DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	selection, err := newSyncSelection(c.skipResourceTypes, c.skipEntitlements)
 	if err != nil {
 		return nil, err
 	}
-	return &Connector{
-		client: rootlyClient,
-	}, nil
+	for resourceTypeID, patterns := range c.nameFilters {
+		err = selection.setNameFilter(resourceTypeID, patterns.include, patterns.exclude)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.selection = selection
+
+	rootlyClient, err := client.NewClient(
+		ctx,
+		c.baseURL,
+		apiKey,
+		c.pageSize,
+		client.WithRetryPolicy(c.retryMaxAttempts, c.retryMaxElapsed),
+		client.WithConcurrency(c.concurrency),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.client = rootlyClient
+	return c, nil
 }
@@ -0,0 +1,205 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnector_Validate(t *testing.T) {
+	tests := []struct {
+		name              string
+		statuses          map[string]int
+		opts              []Option
+		wantErr           string
+		wantResourceTypes []string
+		wantEntitlements  []string
+	}{
+		{
+			name:              "all readable",
+			wantResourceTypes: []string{"user", "team", "secret", "schedule"},
+			wantEntitlements:  []string{"team:admin", "team:member", "schedule:owner", "schedule:member", "schedule:on-call"},
+		},
+		{
+			name: "optional resource types forbidden",
+			statuses: map[string]int{
+				client.ListSecretsAPIEndpoint:   http.StatusForbidden,
+				client.ListSchedulesAPIEndpoint: http.StatusForbidden,
+			},
+			wantResourceTypes: []string{"user", "team"},
+			wantEntitlements:  []string{"team:admin", "team:member"},
+		},
+		{
+			name: "forbidden resource type is skipped",
+			statuses: map[string]int{
+				client.ListSecretsAPIEndpoint: http.StatusForbidden,
+			},
+			opts:              []Option{WithSkippedResourceTypes("secret")},
+			wantResourceTypes: []string{"user", "team", "schedule"},
+			wantEntitlements:  []string{"team:admin", "team:member", "schedule:owner", "schedule:member", "schedule:on-call"},
+		},
+		{
+			name:              "skipped entitlements",
+			opts:              []Option{WithSkippedEntitlements("team:admin", "schedule:on-call")},
+			wantResourceTypes: []string{"user", "team", "secret", "schedule"},
+			wantEntitlements:  []string{"team:member", "schedule:owner", "schedule:member"},
+		},
+		{
+			name: "required resource type forbidden",
+			statuses: map[string]int{
+				client.ListUsersAPIEndpoint: http.StatusForbidden,
+			},
+			wantErr: "the API key can't read user",
+		},
+		{
+			name: "invalid API key",
+			statuses: map[string]int{
+				client.ListTeamsAPIEndpoint: http.StatusUnauthorized,
+			},
+			wantErr: "the API key is invalid",
+		},
+		{
+			name: "server error",
+			statuses: map[string]int{
+				client.ListSchedulesAPIEndpoint: http.StatusInternalServerError,
+			},
+			opts:    []Option{WithRetryPolicy(1, 0)},
+			wantErr: "validation failed for schedule",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				require.Equal(t, "1", request.URL.Query().Get("page[size]"))
+				writer.Header().Set(uhttp.ContentType, "application/json")
+				if code, ok := tc.statuses[request.URL.Path]; ok {
+					writer.WriteHeader(code)
+					_, _ = writer.Write([]byte(`{"errors": [{"title": "Request failed", "status": "` + http.StatusText(code) + `"}]}`))
+					return
+				}
+				_, _ = writer.Write([]byte(`{"data": [], "links": {"next": null}}`))
+			}))
+			t.Cleanup(server.Close)
+
+			ctx := context.Background()
+			c, err := New(ctx, "test-api-key", append(tc.opts, WithBaseURL(server.URL))...)
+			require.NoError(t, err)
+
+			annos, err := c.Validate(ctx)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			resourceTypes := &v2.ResourceTypesServiceListResourceTypesResponse{}
+			ok, err := annos.Pick(resourceTypes)
+			require.NoError(t, err)
+			require.True(t, ok)
+			var resourceTypeIDs []string
+			for _, resourceType := range resourceTypes.List {
+				resourceTypeIDs = append(resourceTypeIDs, resourceType.Id)
+			}
+			require.Equal(t, tc.wantResourceTypes, resourceTypeIDs)
+			entitlements := &v2.EntitlementsServiceListEntitlementsResponse{}
+			ok, err = annos.Pick(entitlements)
+			require.NoError(t, err)
+			require.True(t, ok)
+			var entitlementIDs []string
+			for _, entitlement := range entitlements.List {
+				entitlementIDs = append(entitlementIDs, entitlement.Id)
+			}
+			require.Equal(t, tc.wantEntitlements, entitlementIDs)
+
+			// the resource types the API key can't read are left out of the sync
+			var syncedResourceTypeIDs []string
+			for _, syncer := range c.ResourceSyncers(ctx) {
+				syncedResourceTypeIDs = append(syncedResourceTypeIDs, syncer.ResourceType(ctx).Id)
+			}
+			require.Equal(t, tc.wantResourceTypes, syncedResourceTypeIDs)
+		})
+	}
+}
+
+func Test_userBuilder_ListAnnotatesRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		writer.Header().Set("X-RateLimit-Limit", "3000")
+		writer.Header().Set("X-RateLimit-Remaining", "2999")
+		writer.Header().Set("X-RateLimit-Reset", "60")
+		_, _ = writer.Write([]byte(`{"data": [], "links": {"next": null}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, server.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+
+	_, _, annos, err := newUserBuilder(rootlyClient, true).List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	rateLimit := &v2.RateLimitDescription{}
+	ok, err := annos.Pick(rateLimit)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, v2.RateLimitDescription_STATUS_OK, rateLimit.Status)
+	require.Equal(t, int64(3000), rateLimit.Limit)
+	require.Equal(t, int64(2999), rateLimit.Remaining)
+}
+
+func TestConnector_Asset(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/v1/users/97487":
+			writer.Header().Set(uhttp.ContentType, "application/json")
+			_, _ = writer.Write([]byte(`{"data": {"id": "97487", "type": "users", "attributes": {
+				"name": "Sam Testsalot", "email": "sam@example.com", "avatar_url": "` + server.URL + `/avatars/97487.png"
+			}}}`))
+		case "/v1/teams/test-team-guid":
+			writer.Header().Set(uhttp.ContentType, "application/json")
+			_, _ = writer.Write([]byte(`{"data": {"id": "test-team-guid", "type": "groups", "attributes": {"name": "Team1"}}}`))
+		case "/avatars/97487.png":
+			writer.Header().Set(uhttp.ContentType, "image/png")
+			_, _ = writer.Write([]byte("png"))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c, err := New(ctx, "test-api-key", WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	user, err := c.client.GetUser(ctx, "97487")
+	require.NoError(t, err)
+	userResource, err := newUserResource(*user, nil, nil)
+	require.NoError(t, err)
+	userTrait := &v2.UserTrait{}
+	require.NoError(t, userResource.Annotations[0].UnmarshalTo(userTrait))
+	require.Equal(t, "user:97487", userTrait.GetIcon().GetId())
+
+	contentType, body, err := c.Asset(ctx, userTrait.GetIcon())
+	require.NoError(t, err)
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "image/png", contentType)
+	require.Equal(t, "png", string(content))
+
+	// the team has no icon
+	_, _, err = c.Asset(ctx, assetRef(teamResourceType.Id, "test-team-guid"))
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, _, err = c.Asset(ctx, &v2.AssetRef{Id: "secret:test-secret-guid"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,333 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const auditFeedID = "rootly_audit_log"
+
+// auditChangeEvents are the audit events recording a change to the audited item, rather than an access to it.
+var auditChangeEvents = []string{"create", "update", "destroy"}
+
+// auditItem describes the synced resource an audited item type maps to.
+type auditItem struct {
+	resourceType *v2.ResourceType
+	// parentIDAttribute is the attribute of the audited object holding the ID of the synced resource, for items that
+	// are part of a synced resource rather than synced themselves, e.g. the schedule of a schedule rotation.
+	parentIDAttribute string
+}
+
+// auditItems maps the audited item types to the synced resources they change.
+var auditItems = map[string]auditItem{
+	"User":             {resourceType: userResourceType},
+	"Group":            {resourceType: teamResourceType},
+	"Team":             {resourceType: teamResourceType},
+	"Secret":           {resourceType: secretResourceType},
+	"Schedule":         {resourceType: scheduleResourceType},
+	"ScheduleRotation": {resourceType: scheduleResourceType, parentIDAttribute: "schedule_id"},
+}
+
+// unsyncedAuditItems are the audited item types changing access that no synced resource maps to, e.g. the roles
+// granting permissions to users, which the connector doesn't sync. Their audits are skipped with a warning, so that
+// the access changes the feed misses show up in the logs.
+var unsyncedAuditItems = []string{"Role"}
+
+// auditCursor is the position of the audit feed in the Rootly audit log. Audits are listed oldest first, so the
+// cursor keeps the creation time of the last audit returned, and the IDs of the audits returned with that exact
+// time, which the next query starting at that time returns again.
+type auditCursor struct {
+	// Next is the link to the next page of the current query, if any.
+	Next string `json:"next,omitempty"`
+	// After is the creation time of the last audit returned.
+	After time.Time `json:"after"`
+	// SeenIDs are the IDs of the audits returned that were created at After.
+	SeenIDs []string `json:"seen_ids,omitempty"`
+}
+
+// auditFeed is an event feed of the changes to, and accesses of, synced resources recorded by the Rootly audit log.
+type auditFeed struct {
+	client    *client.Client
+	selection *syncSelection
+	now       func() time.Time
+}
+
+func (f *auditFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: auditFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_RESOURCE_CHANGE,
+			v2.EventType_EVENT_TYPE_USAGE,
+			// the grants and revocations of team memberships, which have no event type of their own
+			v2.EventType_EVENT_TYPE_UNSPECIFIED,
+		},
+	}
+}
+
+// ListEvents returns the events for the audits created since the cursor, or since earliestEvent when starting
+// without a cursor.
+func (f *auditFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	logger := ctxzap.Extract(ctx)
+	cursor := &auditCursor{}
+	if pToken.Cursor != "" {
+		err := json.Unmarshal([]byte(pToken.Cursor), cursor)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid audit feed cursor: %w", err)
+		}
+	} else if earliestEvent != nil {
+		cursor.After = earliestEvent.AsTime()
+	} else {
+		cursor.After = f.now()
+	}
+
+	audits, nextPage, err := f.client.ListAudits(ctx, cursor.After, pToken.Size, cursor.Next)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var events []*v2.Event
+	syncedTeams := make(map[string]bool)
+	for _, audit := range audits {
+		createdAt, err := time.Parse(time.RFC3339Nano, audit.Attributes.CreatedAt)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid creation time of audit %s: %w", audit.ID, err)
+		}
+		// skip the audits returned by a previous poll
+		if createdAt.Before(cursor.After) || (createdAt.Equal(cursor.After) && slices.Contains(cursor.SeenIDs, audit.ID)) {
+			continue
+		}
+		if createdAt.After(cursor.After) {
+			cursor.After = createdAt
+			cursor.SeenIDs = nil
+		}
+		cursor.SeenIDs = append(cursor.SeenIDs, audit.ID)
+
+		if slices.Contains(unsyncedAuditItems, audit.Attributes.ItemType) {
+			logger.Warn(
+				"Skipping audit of an item type that isn't synced",
+				zap.String("audit.ID", audit.ID),
+				zap.String("audit.ItemType", audit.Attributes.ItemType),
+				zap.String("audit.Event", audit.Attributes.Event),
+			)
+			continue
+		}
+		auditEvents, err := f.auditEvents(audit, createdAt)
+		if err != nil {
+			logger.Warn("Skipping unreadable audit", zap.String("audit.ID", audit.ID), zap.Error(err))
+			continue
+		}
+		auditEvents, err = f.syncedTeamEvents(ctx, auditEvents, syncedTeams)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		events = append(events, auditEvents...)
+	}
+	cursor.Next = nextPage
+
+	nextCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return events, &pagination.StreamState{
+		Cursor:  string(nextCursor),
+		HasMore: nextPage != "",
+	}, withRateLimit(nil, f.client), nil
+}
+
+// auditEvents returns the events for an audit of a synced resource: a resource change referencing the synced
+// resource for a change to it or to one of its parts, so that it's synced again, along with grants and revocations for
+// the team memberships it changed, or a usage by the acting user for an access.
+func (f *auditFeed) auditEvents(audit client.Audit, createdAt time.Time) ([]*v2.Event, error) {
+	item, ok := auditItems[audit.Attributes.ItemType]
+	if !ok || !f.selection.syncsResourceType(item.resourceType.Id) {
+		return nil, nil
+	}
+	resourceType := item.resourceType
+	itemID := audit.Attributes.ItemID
+	if item.parentIDAttribute != "" {
+		var err error
+		itemID, err = auditAttribute(audit, item.parentIDAttribute)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.parentIDAttribute, err)
+		}
+	}
+	if itemID == "" {
+		return nil, nil
+	}
+	resourceID := &v2.ResourceId{
+		ResourceType: resourceType.Id,
+		Resource:     string(itemID),
+	}
+	occurredAt := timestamppb.New(createdAt)
+
+	if !slices.Contains(auditChangeEvents, audit.Attributes.Event) {
+		if audit.Attributes.UserID == nil || item.parentIDAttribute != "" {
+			return nil, nil
+		}
+		return []*v2.Event{{
+			Id:         fmt.Sprintf("audit:%s", audit.ID),
+			OccurredAt: occurredAt,
+			Event: &v2.Event_UsageEvent{UsageEvent: &v2.UsageEvent{
+				TargetResource: &v2.Resource{Id: resourceID},
+				ActorResource:  &v2.Resource{Id: userResourceID(*audit.Attributes.UserID)},
+			}},
+		}}, nil
+	}
+
+	events := []*v2.Event{{
+		Id:         fmt.Sprintf("audit:%s", audit.ID),
+		OccurredAt: occurredAt,
+		Event: &v2.Event_ResourceChangeEvent{ResourceChangeEvent: &v2.ResourceChangeEvent{
+			ResourceId: resourceID,
+		}},
+	}}
+	if resourceType != teamResourceType || item.parentIDAttribute != "" {
+		return events, nil
+	}
+	for _, change := range []struct {
+		attribute   string
+		entitlement string
+	}{
+		{"user_ids", teamMemberEntitlement},
+		{"admin_ids", teamAdminEntitlement},
+	} {
+		if !f.selection.syncsEntitlement(teamResourceType.Id, change.entitlement) {
+			continue
+		}
+		added, removed, err := auditUserIDChanges(audit.Attributes.ObjectChanges[change.attribute])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", change.attribute, err)
+		}
+		team := &v2.Resource{Id: resourceID}
+		for _, userID := range added {
+			events = append(events, &v2.Event{
+				Id:         fmt.Sprintf("audit:%s:grant:%s:%d", audit.ID, change.entitlement, userID),
+				OccurredAt: occurredAt,
+				Event: &v2.Event_GrantEvent{GrantEvent: &v2.GrantEvent{
+					Grant: grant.NewGrant(team, change.entitlement, userResourceID(userID)),
+				}},
+			})
+		}
+		for _, userID := range removed {
+			events = append(events, &v2.Event{
+				Id:         fmt.Sprintf("audit:%s:revoke:%s:%d", audit.ID, change.entitlement, userID),
+				OccurredAt: occurredAt,
+				Event: &v2.Event_RevokeEvent{RevokeEvent: &v2.RevokeEvent{
+					Entitlement: entitlement.NewAssignmentEntitlement(team, change.entitlement),
+					Principal:   &v2.Resource{Id: userResourceID(userID)},
+				}},
+			})
+		}
+	}
+	return events, nil
+}
+
+// syncedTeamEvents returns the given events without the grants and revocations of the memberships of teams filtered
+// out by name. The teams already looked up during the poll are kept in synced.
+func (f *auditFeed) syncedTeamEvents(ctx context.Context, events []*v2.Event, synced map[string]bool) ([]*v2.Event, error) {
+	var syncedEvents []*v2.Event
+	for _, event := range events {
+		team := event.GetGrantEvent().GetGrant().GetEntitlement().GetResource()
+		if revokeEvent := event.GetRevokeEvent(); revokeEvent != nil {
+			team = revokeEvent.GetEntitlement().GetResource()
+		}
+		if team != nil {
+			ok, err := f.selection.syncsResourceID(ctx, teamResourceType.Id, team.GetId().GetResource(), synced, f.teamName)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		syncedEvents = append(syncedEvents, event)
+	}
+	return syncedEvents, nil
+}
+
+// teamName returns the name of the team with the given ID.
+func (f *auditFeed) teamName(ctx context.Context, teamID string) (string, error) {
+	team, err := f.client.GetTeam(ctx, teamID)
+	if err != nil {
+		return "", err
+	}
+	return team.Attributes.Name, nil
+}
+
+// auditAttribute returns the ID held by an attribute of the audited object, falling back to its latest recorded value
+// when the audit doesn't include the object.
+func auditAttribute(audit client.Audit, name string) (client.FlexibleID, error) {
+	value, ok := audit.Attributes.Object[name]
+	if !ok {
+		change := audit.Attributes.ObjectChanges[name]
+		for i := len(change) - 1; i >= 0 && !ok; i-- {
+			value, ok = change[i], string(change[i]) != "null"
+		}
+	}
+	var id client.FlexibleID
+	if !ok {
+		return id, nil
+	}
+	err := json.Unmarshal(value, &id)
+	return id, err
+}
+
+// auditUserIDChanges returns the user IDs added to and removed from a list of user IDs, given its [before, after]
+// values recorded by an audit.
+func auditUserIDChanges(change []json.RawMessage) ([]int, []int, error) {
+	if len(change) != 2 {
+		return nil, nil, nil
+	}
+	var before, after []int
+	for i, ids := range []*[]int{&before, &after} {
+		if err := json.Unmarshal(change[i], ids); err != nil {
+			return nil, nil, err
+		}
+	}
+	var added, removed []int
+	for _, userID := range after {
+		if !slices.Contains(before, userID) {
+			added = append(added, userID)
+		}
+	}
+	for _, userID := range before {
+		if !slices.Contains(after, userID) {
+			removed = append(removed, userID)
+		}
+	}
+	return added, removed, nil
+}
+
+// userResourceID returns the resource ID of the user with the given Rootly user ID.
+func userResourceID(userID int) *v2.ResourceId {
+	return &v2.ResourceId{
+		ResourceType: userResourceType.Id,
+		Resource:     strconv.Itoa(userID),
+	}
+}
+
+func newAuditFeed(client *client.Client, selection *syncSelection) *auditFeed {
+	return &auditFeed{
+		client:    client,
+		selection: selection,
+		now:       time.Now,
+	}
+}
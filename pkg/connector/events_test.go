@@ -0,0 +1,349 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestAudit returns an audit of a change to the user with the given ID.
+func newTestAudit(id string, userID string, createdAt string) client.Audit {
+	return client.Audit{
+		ID:   id,
+		Type: "audits",
+		Attributes: client.AuditAttributes{
+			ItemType:  "User",
+			ItemID:    client.FlexibleID(userID),
+			Event:     "update",
+			CreatedAt: createdAt,
+		},
+	}
+}
+
+func Test_auditFeed_ListEventsAcrossPolls(t *testing.T) {
+	var mu sync.Mutex
+	audits := []client.Audit{
+		newTestAudit("audit-1", "97487", "2025-04-10T12:00:01.000Z"),
+		newTestAudit("audit-2", "96913", "2025-04-10T12:00:02.000Z"),
+		newTestAudit("audit-3", "98001", "2025-04-10T12:00:02.000Z"),
+		// before the start of the feed
+		newTestAudit("audit-0", "97487", "2025-04-10T11:00:00.000Z"),
+	}
+	addAudits := func(more ...client.Audit) {
+		mu.Lock()
+		defer mu.Unlock()
+		audits = append(audits, more...)
+	}
+	// the audits are filtered by creation time, and sorted by creation time
+	fake := newFakeRootly(t)
+	fake.handle("GET /v1/audits", func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		require.Equal(t, "created_at", query.Get("sort"))
+		from, err := time.Parse(time.RFC3339Nano, query.Get("filter[created_at][gte]"))
+		require.NoError(t, err)
+		to, err := time.Parse(time.RFC3339Nano, query.Get("filter[created_at][lte]"))
+		require.NoError(t, err)
+
+		mu.Lock()
+		var matching []client.Audit
+		for _, audit := range audits {
+			createdAt, err := time.Parse(time.RFC3339Nano, audit.Attributes.CreatedAt)
+			require.NoError(t, err)
+			if !createdAt.Before(from) && !createdAt.After(to) {
+				matching = append(matching, audit)
+			}
+		}
+		mu.Unlock()
+		sort.SliceStable(matching, func(i, j int) bool {
+			return matching[i].Attributes.CreatedAt < matching[j].Attributes.CreatedAt
+		})
+
+		resp := client.AuditsResponse{}
+		resp.Data, resp.Links.Next = paginate(t, request, matching)
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	feed := newAuditFeed(rootlyClient, nil)
+	start := timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC))
+
+	var eventIDs []string
+	var cursor string
+	poll := func() bool {
+		events, state, _, err := feed.ListEvents(ctx, start, &pagination.StreamToken{Size: 2, Cursor: cursor})
+		require.NoError(t, err)
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.Id)
+		}
+		cursor = state.Cursor
+		return state.HasMore
+	}
+
+	// the first poll pages through the audits since the start
+	require.True(t, poll())
+	require.False(t, poll())
+	require.Equal(t, []string{"audit:audit-1", "audit:audit-2", "audit:audit-3"}, eventIDs)
+
+	// polling again doesn't return the same audits
+	require.False(t, poll())
+	require.Len(t, eventIDs, 3)
+
+	// new audits, including one created at the same time as the last ones, are returned once
+	roleAudit := newTestAudit("audit-6", "test-role-guid", "2025-04-10T12:00:04.000Z")
+	roleAudit.Attributes.ItemType = "Role"
+	addAudits(
+		newTestAudit("audit-4", "97487", "2025-04-10T12:00:02.000Z"),
+		newTestAudit("audit-5", "96913", "2025-04-10T12:00:03.000Z"),
+		// roles aren't synced, so their audits are skipped
+		roleAudit,
+	)
+	// the query starting at the last creation time returns a page of audits already seen before the new ones
+	require.True(t, poll())
+	require.True(t, poll())
+	require.False(t, poll())
+	require.Equal(t, []string{
+		"audit:audit-1",
+		"audit:audit-2",
+		"audit:audit-3",
+		"audit:audit-4",
+		"audit:audit-5",
+	}, eventIDs)
+
+	// the cursor moved past the skipped audit
+	require.False(t, poll())
+	require.Len(t, eventIDs, 5)
+}
+
+func Test_auditFeed_ListEventsFiltersTeamsByName(t *testing.T) {
+	// newTeamAudit returns an audit adding a member to the team with the given ID
+	newTeamAudit := func(id string, teamID string) client.Audit {
+		return client.Audit{
+			ID:   id,
+			Type: "audits",
+			Attributes: client.AuditAttributes{
+				ItemType:  "Group",
+				ItemID:    client.FlexibleID(teamID),
+				Event:     "update",
+				CreatedAt: "2025-04-10T12:00:01.000Z",
+				ObjectChanges: map[string][]json.RawMessage{
+					"user_ids": {json.RawMessage(`[96913]`), json.RawMessage(`[96913, 97487]`)},
+				},
+			},
+		}
+	}
+	fake := newFakeRootly(t)
+	fake.handle("GET /v1/audits", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, writer, http.StatusOK, client.AuditsResponse{Data: []client.Audit{
+			newTeamAudit("audit-1", "sre-team-guid"),
+			newTeamAudit("audit-2", "security-team-guid"),
+			newTeamAudit("audit-3", "sre-team-guid"),
+			newTeamAudit("audit-4", "deleted-team-guid"),
+		}})
+	})
+	fake.respond(map[string]string{
+		"GET /v1/teams/sre-team-guid":      sreTeamGetResult,
+		"GET /v1/teams/security-team-guid": securityTeamGetResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, selection.setNameFilter(teamResourceType.Id, "", "^Security$"))
+	feed := newAuditFeed(rootlyClient, selection)
+	start := timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC))
+
+	events, _, _, err := feed.ListEvents(ctx, start, &pagination.StreamToken{Size: 10})
+	require.NoError(t, err)
+	var eventIDs []string
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.Id)
+	}
+	// the teams filtered out, or deleted, are still synced again, which skips them, but their memberships aren't granted
+	require.Equal(t, []string{
+		"audit:audit-1",
+		"audit:audit-1:grant:member:97487",
+		"audit:audit-2",
+		"audit:audit-3",
+		"audit:audit-3:grant:member:97487",
+		"audit:audit-4",
+	}, eventIDs)
+	// each team is looked up once per poll
+	require.Equal(t, 1, fake.requestCount("/v1/teams/sre-team-guid"))
+}
+
+func Test_auditFeed_auditEvents(t *testing.T) {
+	feed := newAuditFeed(nil, nil)
+	createdAt := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	actorID := 96913
+
+	t.Run("team membership change", func(t *testing.T) {
+		events, err := feed.auditEvents(client.Audit{
+			ID: "audit-1",
+			Attributes: client.AuditAttributes{
+				ItemType: "Group",
+				ItemID:   "sre-team-guid",
+				Event:    "update",
+				UserID:   &actorID,
+				ObjectChanges: map[string][]json.RawMessage{
+					"user_ids":  {json.RawMessage(`[96913, 97487]`), json.RawMessage(`[96913, 98001]`)},
+					"admin_ids": {json.RawMessage(`null`), json.RawMessage(`[96913]`)},
+				},
+			},
+		}, createdAt)
+		require.NoError(t, err)
+		require.Len(t, events, 4)
+
+		require.Equal(t, "audit:audit-1", events[0].Id)
+		require.Equal(t, "sre-team-guid", events[0].GetResourceChangeEvent().GetResourceId().GetResource())
+		require.Equal(t, createdAt, events[0].OccurredAt.AsTime())
+
+		require.Equal(t, "team:sre-team-guid:member:user:98001", events[1].GetGrantEvent().GetGrant().GetId())
+		require.Equal(t, "team:sre-team-guid:member", events[2].GetRevokeEvent().GetEntitlement().GetId())
+		require.Equal(t, "97487", events[2].GetRevokeEvent().GetPrincipal().GetId().GetResource())
+		require.Equal(t, "team:sre-team-guid:admin:user:96913", events[3].GetGrantEvent().GetGrant().GetId())
+	})
+
+	t.Run("secret access", func(t *testing.T) {
+		events, err := feed.auditEvents(client.Audit{
+			ID: "audit-2",
+			Attributes: client.AuditAttributes{
+				ItemType: "Secret",
+				ItemID:   "test-secret-guid",
+				Event:    "view",
+				UserID:   &actorID,
+			},
+		}, createdAt)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		usage := events[0].GetUsageEvent()
+		require.Equal(t, &v2.ResourceId{ResourceType: "secret", Resource: "test-secret-guid"}, usage.GetTargetResource().GetId())
+		require.Equal(t, &v2.ResourceId{ResourceType: "user", Resource: "96913"}, usage.GetActorResource().GetId())
+	})
+
+	t.Run("unsynced item type", func(t *testing.T) {
+		events, err := feed.auditEvents(client.Audit{
+			ID:         "audit-3",
+			Attributes: client.AuditAttributes{ItemType: "Incident", ItemID: "test-incident-guid", Event: "update"},
+		}, createdAt)
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+}
+
+func Test_auditFeed_auditEventsResourceIDs(t *testing.T) {
+	createdAt := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	actorID := 96913
+	skipSecrets, err := newSyncSelection([]string{"secret"}, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		selection *syncSelection
+		audit     client.AuditAttributes
+		// expected is the resource referenced by the resource change event, if any
+		expected *v2.ResourceId
+	}{
+		{
+			name:     "user",
+			audit:    client.AuditAttributes{ItemType: "User", ItemID: "97487", Event: "update"},
+			expected: &v2.ResourceId{ResourceType: "user", Resource: "97487"},
+		},
+		{
+			name:     "group",
+			audit:    client.AuditAttributes{ItemType: "Group", ItemID: "sre-team-guid", Event: "create"},
+			expected: &v2.ResourceId{ResourceType: "team", Resource: "sre-team-guid"},
+		},
+		{
+			name:     "team",
+			audit:    client.AuditAttributes{ItemType: "Team", ItemID: "sre-team-guid", Event: "update"},
+			expected: &v2.ResourceId{ResourceType: "team", Resource: "sre-team-guid"},
+		},
+		{
+			name:     "secret",
+			audit:    client.AuditAttributes{ItemType: "Secret", ItemID: "test-secret-guid", Event: "update"},
+			expected: &v2.ResourceId{ResourceType: "secret", Resource: "test-secret-guid"},
+		},
+		{
+			name:     "deleted schedule",
+			audit:    client.AuditAttributes{ItemType: "Schedule", ItemID: "test-schedule-guid", Event: "destroy"},
+			expected: &v2.ResourceId{ResourceType: "schedule", Resource: "test-schedule-guid"},
+		},
+		{
+			name: "schedule rotation",
+			audit: client.AuditAttributes{
+				ItemType: "ScheduleRotation",
+				ItemID:   "test-rotation-guid",
+				Event:    "update",
+				Object:   map[string]json.RawMessage{"schedule_id": json.RawMessage(`"test-schedule-guid"`)},
+			},
+			expected: &v2.ResourceId{ResourceType: "schedule", Resource: "test-schedule-guid"},
+		},
+		{
+			name: "schedule rotation without object",
+			audit: client.AuditAttributes{
+				ItemType: "ScheduleRotation",
+				ItemID:   "test-rotation-guid",
+				Event:    "create",
+				ObjectChanges: map[string][]json.RawMessage{
+					"schedule_id": {json.RawMessage(`null`), json.RawMessage(`"test-schedule-guid"`)},
+				},
+			},
+			expected: &v2.ResourceId{ResourceType: "schedule", Resource: "test-schedule-guid"},
+		},
+		{
+			name:  "schedule rotation without schedule",
+			audit: client.AuditAttributes{ItemType: "ScheduleRotation", ItemID: "test-rotation-guid", Event: "update"},
+		},
+		{
+			name: "schedule rotation access",
+			audit: client.AuditAttributes{
+				ItemType: "ScheduleRotation",
+				ItemID:   "test-rotation-guid",
+				Event:    "view",
+				UserID:   &actorID,
+				Object:   map[string]json.RawMessage{"schedule_id": json.RawMessage(`"test-schedule-guid"`)},
+			},
+		},
+		{
+			name:  "missing item ID",
+			audit: client.AuditAttributes{ItemType: "User", Event: "update"},
+		},
+		{
+			name:  "unsynced item type",
+			audit: client.AuditAttributes{ItemType: "Incident", ItemID: "test-incident-guid", Event: "update"},
+		},
+		{
+			name:      "skipped resource type",
+			selection: skipSecrets,
+			audit:     client.AuditAttributes{ItemType: "Secret", ItemID: "test-secret-guid", Event: "update"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed := newAuditFeed(nil, tc.selection)
+			events, err := feed.auditEvents(client.Audit{ID: "audit-1", Attributes: tc.audit}, createdAt)
+			require.NoError(t, err)
+			if tc.expected == nil {
+				require.Empty(t, events)
+				return
+			}
+			require.Len(t, events, 1)
+			require.Equal(t, "audit:audit-1", events[0].Id)
+			require.Equal(t, tc.expected, events[0].GetResourceChangeEvent().GetResourceId())
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package connector
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRootly is a fake Rootly API shared by the connector tests. Tests register the endpoints they need with
+// http.ServeMux patterns, e.g. "GET /v1/users/{id}", and other requests respond 404 like the Rootly API. It records
+// the requests it receives, so that tests can check what was fetched or written.
+type fakeRootly struct {
+	*httptest.Server
+	t   *testing.T
+	mux *http.ServeMux
+
+	mu       sync.Mutex
+	requests []fakeRequest
+}
+
+// fakeRequest is a request received by fakeRootly.
+type fakeRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+func newFakeRootly(t *testing.T) *fakeRootly {
+	f := &fakeRootly{
+		t:   t,
+		mux: http.NewServeMux(),
+	}
+	f.mux.HandleFunc("/", func(writer http.ResponseWriter, _ *http.Request) {
+		notFound(writer)
+	})
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeRootly) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	require.NoError(f.t, err)
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > 0 {
+		// like the Rootly API, writes must be JSON:API documents
+		require.Equal(f.t, "application/vnd.api+json", request.Header.Get(uhttp.ContentType))
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, fakeRequest{
+		method: request.Method,
+		path:   request.URL.Path,
+		body:   strings.TrimSpace(string(body)),
+	})
+	f.mu.Unlock()
+
+	writer.Header().Set(uhttp.ContentType, "application/json")
+	f.mux.ServeHTTP(writer, request)
+}
+
+// handle registers the handler of the requests matching the given http.ServeMux pattern.
+func (f *fakeRootly) handle(pattern string, handler http.HandlerFunc) {
+	f.mux.HandleFunc(pattern, handler)
+}
+
+// respond registers fixed JSON responses by http.ServeMux pattern.
+func (f *fakeRootly) respond(responses map[string]string) {
+	for pattern, body := range responses {
+		f.handle(pattern, func(writer http.ResponseWriter, _ *http.Request) {
+			_, _ = writer.Write([]byte(body))
+		})
+	}
+}
+
+// requestCount returns the number of requests received for the given path, whatever their method.
+func (f *fakeRootly) requestCount(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int
+	for _, request := range f.requests {
+		if request.path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// bodies returns the bodies of the requests received with the given method, by path.
+func (f *fakeRootly) bodies(method string) map[string][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bodies := make(map[string][]string)
+	for _, request := range f.requests {
+		if request.method == method {
+			bodies[request.path] = append(bodies[request.path], request.body)
+		}
+	}
+	return bodies
+}
+
+// decodeBodies returns the bodies of the requests received with the given method and path, decoded.
+func decodeBodies[T any](t *testing.T, f *fakeRootly, method string, path string) []T {
+	var decoded []T
+	for _, body := range f.bodies(method)[path] {
+		var v T
+		require.NoError(t, json.Unmarshal([]byte(body), &v))
+		decoded = append(decoded, v)
+	}
+	return decoded
+}
+
+// writeJSON writes the given value as the JSON response, with the given status code.
+func writeJSON(t *testing.T, writer http.ResponseWriter, statusCode int, v any) {
+	writer.WriteHeader(statusCode)
+	require.NoError(t, json.NewEncoder(writer).Encode(v))
+}
+
+// notFound writes the response of the Rootly API for a resource that doesn't exist.
+func notFound(writer http.ResponseWriter) {
+	writer.WriteHeader(http.StatusNotFound)
+	_, _ = writer.Write([]byte(`{"errors": [{"title": "Not found", "status": "404"}]}`))
+}
+
+// paginate returns the page of items requested, along with the link to the next page, if any, like the Rootly API.
+func paginate[T any](t *testing.T, request *http.Request, items []T) ([]T, string) {
+	query := request.URL.Query()
+	pageNumber, err := strconv.Atoi(query.Get("page[number]"))
+	require.NoError(t, err)
+	pageSize, err := strconv.Atoi(query.Get("page[size]"))
+	require.NoError(t, err)
+
+	start := min((pageNumber-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+	if end == len(items) {
+		return items[start:end], ""
+	}
+	query.Set("page[number]", strconv.Itoa(pageNumber+1))
+	next := *request.URL
+	next.RawQuery = query.Encode()
+	return items[start:end], "http://" + request.Host + next.String()
+}
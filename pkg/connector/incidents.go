@@ -0,0 +1,247 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const incidentFeedID = "rootly_incident_participation"
+
+// openIncidentStatuses are the statuses of the incidents still being worked on, which users take part in.
+var openIncidentStatuses = []string{"in_triage", "started", "mitigated"}
+
+// incidentCursor is the position of the incident feed. Each query covers the time window from Since until Until: it
+// lists the role assignments and timeline events created within the window, so that the next query picks up exactly
+// where it ended, of the incidents that may have some. Those are listed per incident, so the query first lists the
+// open incidents, regardless of whether adding a role assignment or a timeline event updated them, and then the other
+// incidents updated since the start of the window, e.g. because they were resolved meanwhile. Activity on an incident
+// that was already resolved before the window is only found when it updated the incident.
+type incidentCursor struct {
+	// Updated is set once the open incidents were listed, when the incidents updated within the window are listed.
+	Updated bool `json:"updated,omitempty"`
+	// Open holds the IDs of the open incidents listed by the current query, which aren't listed again as updated.
+	Open []string `json:"open,omitempty"`
+	// Next is the link to the next page of incidents of the current query, if any.
+	Next string `json:"next,omitempty"`
+	// Since is the start of the window of the current query, included.
+	Since time.Time `json:"since"`
+	// Until is the end of the window of the current query, excluded, or zero before the query starts.
+	Until time.Time `json:"until"`
+}
+
+// incidentFeed is an event feed of the users taking part in Rootly incidents, i.e. being assigned an incident role or
+// appearing on an incident timeline, e.g. by joining the incident channel, which grants them access to incident data.
+// The events target the synced user resources, since incidents aren't synced.
+type incidentFeed struct {
+	client *client.Client
+	now    func() time.Time
+}
+
+func (f *incidentFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: incidentFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_USAGE,
+		},
+	}
+}
+
+// ListEvents returns a usage event for each incident role assignment and user timeline event created since the
+// cursor, or since earliestEvent when starting without a cursor.
+func (f *incidentFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	logger := ctxzap.Extract(ctx)
+	cursor := &incidentCursor{}
+	if pToken.Cursor != "" {
+		err := json.Unmarshal([]byte(pToken.Cursor), cursor)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid incident feed cursor: %w", err)
+		}
+	} else if earliestEvent != nil {
+		cursor.Since = earliestEvent.AsTime()
+	} else {
+		cursor.Since = f.now()
+	}
+	if cursor.Until.IsZero() {
+		cursor.Until = f.now()
+	}
+
+	listOption := client.WithStatuses(openIncidentStatuses...)
+	if cursor.Updated {
+		listOption = client.WithUpdatedSince(cursor.Since)
+	}
+	incidents, nextPage, err := f.client.ListIncidents(ctx, pToken.Size, cursor.Next, listOption)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var events []*v2.Event
+	for _, incident := range incidents {
+		if !cursor.Updated {
+			if !slices.Contains(openIncidentStatuses, incident.Attributes.Status) {
+				// resolved meanwhile, so it's listed as updated
+				continue
+			}
+			cursor.Open = append(cursor.Open, incident.ID)
+		} else if slices.Contains(cursor.Open, incident.ID) {
+			continue
+		}
+		incidentEvents, err := f.incidentEvents(ctx, incident, cursor.Since, cursor.Until)
+		if client.IsNotFound(err) {
+			logger.Debug("Skipping incident deleted while listing events", zap.String("incident.ID", incident.ID))
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		events = append(events, incidentEvents...)
+	}
+
+	hasMore := true
+	switch {
+	case nextPage != "":
+		cursor.Next = nextPage
+	case !cursor.Updated:
+		// the open incidents are listed, the incidents updated within the window are next
+		cursor.Updated = true
+		cursor.Next = ""
+	default:
+		// the query is over, the next one starts where its window ended
+		cursor = &incidentCursor{Since: cursor.Until}
+		hasMore = false
+	}
+	nextCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return events, &pagination.StreamState{
+		Cursor:  string(nextCursor),
+		HasMore: hasMore,
+	}, withRateLimit(nil, f.client), nil
+}
+
+// incidentEvents returns the usage events for the role assignments and the timeline events attributed to a user of
+// an incident, created within the given window.
+func (f *incidentFeed) incidentEvents(
+	ctx context.Context,
+	incident client.Incident,
+	since time.Time,
+	until time.Time,
+) ([]*v2.Event, error) {
+	logger := ctxzap.Extract(ctx)
+	assignments, err := f.client.ListAllIncidentRoleAssignments(ctx, incident.ID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	var events []*v2.Event
+	for _, assignment := range assignments {
+		createdAt, ok := parseWithin(assignment.Attributes.CreatedAt, since, until)
+		if !ok || assignment.Attributes.UserID == nil {
+			continue
+		}
+		events = append(events, incidentUsageEvent(
+			fmt.Sprintf("incident:%s:role_assignment:%s", incident.ID, assignment.ID),
+			createdAt,
+			incident,
+			*assignment.Attributes.UserID,
+			map[string]interface{}{
+				"activity":         "incident_role_assigned",
+				"incident_role_id": string(assignment.Attributes.IncidentRoleID),
+			},
+		))
+	}
+
+	timeline, err := f.client.ListAllIncidentEvents(ctx, incident.ID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	for _, timelineEvent := range timeline {
+		createdAt, ok := parseWithin(timelineEvent.Attributes.CreatedAt, since, until)
+		if !ok || timelineEvent.Attributes.UserID == nil {
+			continue
+		}
+		occurredAt, err := time.Parse(time.RFC3339Nano, timelineEvent.Attributes.OccurredAt)
+		if err != nil {
+			logger.Debug(
+				"Using the creation time of an incident event without occurrence time",
+				zap.String("incident_event.ID", timelineEvent.ID),
+			)
+			occurredAt = createdAt
+		}
+		events = append(events, incidentUsageEvent(
+			fmt.Sprintf("incident:%s:event:%s", incident.ID, timelineEvent.ID),
+			occurredAt,
+			incident,
+			*timelineEvent.Attributes.UserID,
+			map[string]interface{}{
+				"activity": "incident_event",
+				"event":    timelineEvent.Attributes.Event,
+			},
+		))
+	}
+	return events, nil
+}
+
+// incidentUsageEvent returns a usage event of a user taking part in an incident, annotated with the details of the
+// activity. Incidents aren't synced, so the event targets the user, whose access to incident data it's about, and
+// the incident is identified in the details.
+func incidentUsageEvent(
+	id string,
+	occurredAt time.Time,
+	incident client.Incident,
+	userID int,
+	details map[string]interface{},
+) *v2.Event {
+	user := &v2.Resource{Id: userResourceID(userID)}
+	event := &v2.Event{
+		Id:         id,
+		OccurredAt: timestamppb.New(occurredAt),
+		Event: &v2.Event_UsageEvent{UsageEvent: &v2.UsageEvent{
+			TargetResource: user,
+			ActorResource:  user,
+		}},
+	}
+	details["incident_id"] = incident.ID
+	details["incident_title"] = incident.Attributes.Title
+	if incident.Attributes.URL != "" {
+		details["incident_url"] = incident.Attributes.URL
+	}
+	if st, err := structpb.NewStruct(details); err == nil {
+		var annos annotations.Annotations
+		annos.Update(st)
+		event.Annotations = annos
+	}
+	return event
+}
+
+// parseWithin parses a timestamp, and reports whether it's within the window starting at since, included, and ending
+// at until, excluded. Rootly filters by the window already, this guards against the filter being ignored.
+func parseWithin(timestamp string, since time.Time, until time.Time) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil || t.Before(since) || !t.Before(until) {
+		return t, false
+	}
+	return t, true
+}
+
+func newIncidentFeed(client *client.Client) *incidentFeed {
+	return &incidentFeed{
+		client: client,
+		now:    time.Now,
+	}
+}
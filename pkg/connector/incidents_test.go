@@ -0,0 +1,241 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeIncidents holds the incidents served by serveIncidents, along with their role assignments and timeline events.
+type fakeIncidents struct {
+	mu          sync.Mutex
+	incidents   []client.Incident
+	assignments map[string][]client.IncidentRoleAssignment
+	events      map[string][]client.IncidentEvent
+}
+
+// serveIncidents registers the endpoints listing the incidents, filtered by status or update time, and their role assignments
+// and timeline events, filtered by creation time, on the fake Rootly API. Deleted incidents respond 404.
+func serveIncidents(t *testing.T, fake *fakeRootly, incidents *fakeIncidents) {
+	parse := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		require.NoError(t, err)
+		return parsed
+	}
+	fake.handle("GET /v1/incidents", func(writer http.ResponseWriter, request *http.Request) {
+		incidents.mu.Lock()
+		defer incidents.mu.Unlock()
+		require.Equal(t, "created_at", request.URL.Query().Get("sort"))
+		var matching []client.Incident
+		for _, incident := range incidents.incidents {
+			if statuses := request.URL.Query().Get("filter[status]"); statuses != "" {
+				if slices.Contains(strings.Split(statuses, ","), incident.Attributes.Status) {
+					matching = append(matching, incident)
+				}
+				continue
+			}
+			if !parse(incident.Attributes.UpdatedAt).Before(parse(request.URL.Query().Get("filter[updated_at][gte]"))) {
+				matching = append(matching, incident)
+			}
+		}
+		resp := client.IncidentsResponse{}
+		resp.Data, resp.Links.Next = paginate(t, request, matching)
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+	// within returns whether the given creation time is within the requested window
+	within := func(request *http.Request, createdAt string) bool {
+		created := parse(createdAt)
+		return !created.Before(parse(request.URL.Query().Get("filter[created_at][gte]"))) &&
+			created.Before(parse(request.URL.Query().Get("filter[created_at][lt]")))
+	}
+	fake.handle("GET /v1/incidents/{id}/incident_role_assignments", func(writer http.ResponseWriter, request *http.Request) {
+		incidents.mu.Lock()
+		defer incidents.mu.Unlock()
+		if strings.HasPrefix(request.PathValue("id"), "deleted") {
+			notFound(writer)
+			return
+		}
+		resp := client.IncidentRoleAssignmentsResponse{}
+		for _, assignment := range incidents.assignments[request.PathValue("id")] {
+			if within(request, assignment.Attributes.CreatedAt) {
+				resp.Data = append(resp.Data, assignment)
+			}
+		}
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+	fake.handle("GET /v1/incidents/{id}/events", func(writer http.ResponseWriter, request *http.Request) {
+		incidents.mu.Lock()
+		defer incidents.mu.Unlock()
+		if strings.HasPrefix(request.PathValue("id"), "deleted") {
+			notFound(writer)
+			return
+		}
+		resp := client.IncidentEventsResponse{}
+		for _, event := range incidents.events[request.PathValue("id")] {
+			if within(request, event.Attributes.CreatedAt) {
+				resp.Data = append(resp.Data, event)
+			}
+		}
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+}
+
+// newTestIncident returns an incident with the given status, last updated at the given time.
+func newTestIncident(id string, status string, updatedAt string) client.Incident {
+	return client.Incident{
+		ID:         id,
+		Type:       "incidents",
+		Attributes: client.IncidentAttributes{Title: "Incident " + id, Status: status, UpdatedAt: updatedAt},
+	}
+}
+
+func Test_incidentFeed_ListEventsAcrossPolls(t *testing.T) {
+	userID := 97487
+	adminID := 96913
+	incidents := &fakeIncidents{
+		incidents: []client.Incident{
+			newTestIncident("incident-1", "resolved", "2025-04-10T12:00:05.000Z"),
+			newTestIncident("deleted-incident", "resolved", "2025-04-10T12:00:05.000Z"),
+			newTestIncident("incident-2", "started", "2025-04-10T12:00:06.000Z"),
+			// not updated since the start of the feed
+			newTestIncident("incident-0", "resolved", "2025-04-10T11:00:00.000Z"),
+			// open, but not updated by the events created since the start of the feed
+			newTestIncident("incident-3", "mitigated", "2025-04-10T11:00:00.000Z"),
+		},
+		assignments: map[string][]client.IncidentRoleAssignment{
+			"incident-1": {{ID: "assignment-1", Attributes: client.IncidentRoleAssignmentAttributes{
+				IncidentRoleID: "commander-role-guid",
+				UserID:         &adminID,
+				CreatedAt:      "2025-04-10T12:00:01.000Z",
+			}}},
+		},
+		events: map[string][]client.IncidentEvent{
+			"incident-1": {
+				{ID: "event-1", Attributes: client.IncidentEventAttributes{
+					Event:      "Jane Doe joined the incident channel",
+					UserID:     &userID,
+					OccurredAt: "2025-04-10T12:00:02.000Z",
+					CreatedAt:  "2025-04-10T12:00:02.000Z",
+				}},
+				// not attributed to a user
+				{ID: "event-2", Attributes: client.IncidentEventAttributes{
+					Event:     "Severity changed to SEV1",
+					CreatedAt: "2025-04-10T12:00:03.000Z",
+				}},
+			},
+			"incident-2": {
+				{ID: "event-3", Attributes: client.IncidentEventAttributes{
+					Event:     "Jane Doe joined the incident channel",
+					UserID:    &userID,
+					CreatedAt: "2025-04-10T12:00:06.000Z",
+				}},
+			},
+			"incident-3": {
+				{ID: "event-5", Attributes: client.IncidentEventAttributes{
+					Event:     "Jane Doe joined the incident channel",
+					UserID:    &userID,
+					CreatedAt: "2025-04-10T12:00:07.000Z",
+				}},
+			},
+		},
+	}
+	fake := newFakeRootly(t)
+	serveIncidents(t, fake, incidents)
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	feed := newIncidentFeed(rootlyClient)
+	now := time.Date(2025, 4, 10, 12, 0, 10, 0, time.UTC)
+	feed.now = func() time.Time { return now }
+	start := timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC))
+
+	var eventIDs []string
+	var cursor string
+	// poll pages through the incidents and returns the number of requests it took
+	poll := func() int {
+		for requests := 1; ; requests++ {
+			events, state, _, err := feed.ListEvents(ctx, start, &pagination.StreamToken{Size: 2, Cursor: cursor})
+			require.NoError(t, err)
+			for _, event := range events {
+				eventIDs = append(eventIDs, event.Id)
+			}
+			cursor = state.Cursor
+			if !state.HasMore {
+				return requests
+			}
+		}
+	}
+
+	// the first poll pages through the open incidents, and then through the other incidents updated since the
+	// start, skipping the deleted one
+	require.Equal(t, 3, poll())
+	require.Equal(t, []string{
+		"incident:incident-2:event:event-3",
+		"incident:incident-3:event:event-5",
+		"incident:incident-1:role_assignment:assignment-1",
+		"incident:incident-1:event:event-1",
+	}, eventIDs)
+
+	// polling again doesn't return the same events
+	now = now.Add(10 * time.Second)
+	poll()
+	require.Len(t, eventIDs, 4)
+
+	// new events of a previously seen incident are returned once
+	incidents.mu.Lock()
+	incidents.incidents[0].Attributes.UpdatedAt = "2025-04-10T12:00:25.000Z"
+	incidents.events["incident-1"] = append(incidents.events["incident-1"], client.IncidentEvent{
+		ID: "event-4",
+		Attributes: client.IncidentEventAttributes{
+			Event:     "Jane Doe posted an update",
+			UserID:    &userID,
+			CreatedAt: "2025-04-10T12:00:25.000Z",
+		},
+	})
+	incidents.mu.Unlock()
+	now = now.Add(10 * time.Second)
+	poll()
+	poll()
+	require.Equal(t, "incident:incident-1:event:event-4", eventIDs[len(eventIDs)-1])
+	require.Len(t, eventIDs, 5)
+}
+
+func Test_incidentFeed_incidentUsageEvent(t *testing.T) {
+	occurredAt := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	incident := client.Incident{
+		ID:         "incident-1",
+		Type:       "incidents",
+		Attributes: client.IncidentAttributes{Title: "Database outage", URL: "https://rootly.com/account/incidents/1"},
+	}
+
+	event := incidentUsageEvent("incident:incident-1:role_assignment:assignment-1", occurredAt, incident, 96913,
+		map[string]interface{}{"activity": "incident_role_assigned", "incident_role_id": "commander-role-guid"})
+
+	require.Equal(t, occurredAt, event.OccurredAt.AsTime())
+	usage := event.GetUsageEvent()
+	// incidents aren't synced, so the event targets the user taking part in it
+	require.Equal(t, &v2.ResourceId{ResourceType: "user", Resource: "96913"}, usage.GetTargetResource().GetId())
+	require.Equal(t, &v2.ResourceId{ResourceType: "user", Resource: "96913"}, usage.GetActorResource().GetId())
+	require.Len(t, event.Annotations, 1)
+	details := &structpb.Struct{}
+	require.NoError(t, event.Annotations[0].UnmarshalTo(details))
+	require.Equal(t, map[string]interface{}{
+		"activity":         "incident_role_assigned",
+		"incident_role_id": "commander-role-guid",
+		"incident_id":      "incident-1",
+		"incident_title":   "Database outage",
+		"incident_url":     "https://rootly.com/account/incidents/1",
+	}, details.AsMap())
+}
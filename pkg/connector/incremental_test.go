@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_incrementalSync_canReuseGrants(t *testing.T) {
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	newScheduleResource := func(updatedAt string, prevETag *v2.ETag) *v2.Resource {
+		resource, err := sdkResource.NewGroupResource(
+			"Production Oncall",
+			scheduleResourceType,
+			"test-schedule-guid",
+			getScheduleTraitOptions(client.Schedule{
+				ID: "test-schedule-guid",
+				Attributes: client.ScheduleAttributes{
+					Name:      "Production Oncall",
+					UpdatedAt: updatedAt,
+				},
+			}),
+		)
+		require.NoError(t, err)
+		if prevETag != nil {
+			// the syncer attaches the ETag from the previous sync alongside the group trait
+			resourceAnnos := annotations.Annotations(resource.Annotations)
+			resourceAnnos.Update(prevETag)
+			resource.Annotations = resourceAnnos
+		}
+		return resource
+	}
+	memberEntitlementID := entitlement.NewEntitlementID(
+		newScheduleResource("", nil),
+		scheduleMemberEntitlement,
+	)
+	rotations := []client.ScheduleRotation{
+		{ID: "test-weekday-rotation-guid", Attributes: client.ScheduleRotationAttributes{UpdatedAt: "2025-04-10T07:00:00.000-00:00"}},
+		{ID: "test-weekend-rotation-guid", Attributes: client.ScheduleRotationAttributes{UpdatedAt: "2025-04-09T07:00:00Z"}},
+	}
+	digest := rotationsDigest(rotations)
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		resource *v2.Resource
+		// rotations are the current rotations of the schedule, if not the unchanged ones
+		rotations []client.ScheduleRotation
+		want      bool
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			want: false,
+		},
+		{
+			name:     "no previous sync",
+			enabled:  true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", nil),
+			want:     false,
+		},
+		{
+			name:    "unchanged since the watermark",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			want: true,
+		},
+		{
+			name:    "updated after the watermark",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T11:00:00.123-00:00", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			want: false,
+		},
+		{
+			name:    "watermark older than the full sync interval",
+			enabled: true,
+			resource: newScheduleResource("2025-04-01T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-08T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			want: false,
+		},
+		{
+			name:    "watermark for a different entitlement",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: "schedule:test-schedule-guid:owner",
+			}),
+			want: false,
+		},
+		{
+			name:    "rotation updated after the watermark",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			rotations: []client.ScheduleRotation{
+				rotations[0],
+				{ID: "test-weekend-rotation-guid", Attributes: client.ScheduleRotationAttributes{UpdatedAt: "2025-04-10T11:00:00Z"}},
+			},
+			want: false,
+		},
+		{
+			name:    "rotation deleted since the watermark",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			rotations: rotations[:1],
+			want:      false,
+		},
+		{
+			name:    "watermark without rotations, from an earlier version",
+			enabled: true,
+			resource: newScheduleResource("2025-04-10T08:00:00Z", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z",
+				EntitlementId: memberEntitlementID,
+			}),
+			want: false,
+		},
+		{
+			name:    "unparseable updated_at",
+			enabled: true,
+			resource: newScheduleResource("yesterday", &v2.ETag{
+				Value:         "2025-04-10T10:00:00Z " + digest,
+				EntitlementId: memberEntitlementID,
+			}),
+			want: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newIncrementalSync(tc.enabled, 24*time.Hour)
+			s.now = func() time.Time { return now }
+			if tc.rotations == nil {
+				tc.rotations = rotations
+			}
+			require.Equal(t, tc.want, s.canReuseGrants(tc.resource, memberEntitlementID, tc.rotations))
+		})
+	}
+}
+
+func Test_incrementalSync_eTag(t *testing.T) {
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+
+	disabled := newIncrementalSync(false, 0)
+	rotations := []client.ScheduleRotation{{ID: "test-weekday-rotation-guid"}}
+	require.Nil(t, disabled.eTag("schedule:test-schedule-guid:member", rotations))
+
+	enabled := newIncrementalSync(true, 0)
+	enabled.now = func() time.Time { return now }
+	require.Equal(t, defaultFullSyncInterval, enabled.fullSyncInterval)
+	eTag := enabled.eTag("schedule:test-schedule-guid:member", rotations)
+	require.Equal(t, "schedule:test-schedule-guid:member", eTag.EntitlementId)
+	require.Equal(t, "2025-04-10T11:55:00Z "+rotationsDigest(rotations), eTag.Value)
+}
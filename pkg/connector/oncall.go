@@ -0,0 +1,304 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const onCallFeedID = "rootly_on_call_shifts"
+
+// onCallCursor is the position of the on-call feed. A poll lists the shifts that make users on-call during the window
+// since the last poll, possibly over several pages, accumulating the time each user is on-call for each schedule, so
+// that every time a user became or stopped being on-call within the window is reported, including for shifts that
+// both started and ended within it. Who was on-call as of the last poll is found again from the shifts covering its
+// time, so that the cursor only keeps a digest of them between polls rather than growing with the number of on-call
+// users.
+type onCallCursor struct {
+	// PolledAt is the time of the last poll.
+	PolledAt time.Time `json:"polled_at"`
+	// OnCallDigests holds a digest of the user IDs on-call for each schedule ID as of the last poll, telling whether
+	// the next poll found them all again.
+	OnCallDigests map[string]string `json:"on_call_digests,omitempty"`
+
+	// Next is the link to the next page of shifts of the current poll, if any.
+	Next string `json:"next,omitempty"`
+	// Until is the time of the current poll, or zero before it starts.
+	Until time.Time `json:"until"`
+	// OnCall holds the periods each user is on-call for each schedule, according to the shifts listed so far.
+	OnCall map[string]map[int][]onCallPeriod `json:"on_call,omitempty"`
+}
+
+// onCallPeriod is a period during which a user is on-call for a schedule, from its start up to but excluding its end.
+type onCallPeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// onCallFeed is an event feed of the users becoming, and stopping being, on-call for a synced schedule, including
+// through override shifts, reported as grants and revocations of the schedule on-call entitlement.
+//
+// A user is on-call as long as a sync would grant them the on-call entitlement, i.e. while they have a shift within
+// the on-call window from now: from the on-call window before their shift starts until it ends.
+type onCallFeed struct {
+	client       *client.Client
+	onCallWindow time.Duration
+	selection    *syncSelection
+	now          func() time.Time
+}
+
+// EventFeedMetadata declares the feed with the unspecified event type. The feed only emits grant and revoke events,
+// but the event types of the SDK are limited to usage and resource change events, which these aren't, so there is
+// no event type to declare them with. The SDK declares its own feed, which may emit any event, the same way.
+func (f *onCallFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: onCallFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_UNSPECIFIED,
+		},
+	}
+}
+
+// ListEvents returns the on-call changes since the last poll, or since earliestEvent when starting without a cursor.
+// The changes are returned along with the last page of shifts of each poll.
+func (f *onCallFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	logger := ctxzap.Extract(ctx)
+	cursor := &onCallCursor{}
+	if pToken.Cursor != "" {
+		err := json.Unmarshal([]byte(pToken.Cursor), cursor)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid on-call feed cursor: %w", err)
+		}
+	} else if earliestEvent != nil {
+		cursor.PolledAt = earliestEvent.AsTime()
+	} else {
+		cursor.PolledAt = f.now()
+	}
+	if cursor.Until.IsZero() {
+		cursor.Until = f.now()
+	}
+
+	// a shift starting within the on-call window after the current poll already makes its user on-call
+	shifts, nextPage, err := f.client.ListShifts(ctx, cursor.PolledAt, cursor.Until.Add(f.onCallWindow), pToken.Size,
+		cursor.Next)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	syncedSchedules := make(map[string]bool)
+	for _, shift := range shifts {
+		synced, err := f.selection.syncsResourceID(ctx, scheduleResourceType.Id, shift.ScheduleID, syncedSchedules,
+			f.scheduleName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !synced {
+			continue
+		}
+		startsAt, err := time.Parse(time.RFC3339Nano, shift.StartsAt)
+		if err != nil {
+			logger.Warn("Skipping shift with an invalid start", zap.String("shift.ID", shift.ShiftID), zap.Error(err))
+			continue
+		}
+		endsAt, err := time.Parse(time.RFC3339Nano, shift.EndsAt)
+		if err != nil {
+			logger.Warn("Skipping shift with an invalid end", zap.String("shift.ID", shift.ShiftID), zap.Error(err))
+			continue
+		}
+		cursor.addPeriod(shift, onCallPeriod{From: startsAt.Add(-f.onCallWindow), To: endsAt})
+	}
+
+	var events []*v2.Event
+	if nextPage != "" {
+		cursor.Next = nextPage
+	} else {
+		for _, scheduleID := range cursor.missingPrevious() {
+			logger.Warn(
+				"Some users on-call as of the last poll weren't found again, e.g. because their shift was deleted, "+
+					"so they won't be revoked until the next sync",
+				zap.String("scheduleID", scheduleID),
+			)
+		}
+		events = cursor.changes()
+		cursor = &onCallCursor{
+			PolledAt:      cursor.Until,
+			OnCallDigests: onCallDigests(cursor.onCallAt(cursor.Until)),
+		}
+	}
+
+	nextCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return events, &pagination.StreamState{
+		Cursor:  string(nextCursor),
+		HasMore: nextPage != "",
+	}, withRateLimit(nil, f.client), nil
+}
+
+// addPeriod records the period a shift of the current poll makes its user on-call, merged with the periods of the
+// user's other shifts it overlaps or adjoins, so that back-to-back shifts count as a single period.
+func (c *onCallCursor) addPeriod(shift client.OnCallShift, period onCallPeriod) {
+	if shift.ScheduleID == "" || !period.To.After(c.PolledAt) || period.From.After(c.Until) {
+		return
+	}
+	if c.OnCall == nil {
+		c.OnCall = make(map[string]map[int][]onCallPeriod)
+	}
+	if c.OnCall[shift.ScheduleID] == nil {
+		c.OnCall[shift.ScheduleID] = make(map[int][]onCallPeriod)
+	}
+	var merged []onCallPeriod
+	for _, other := range c.OnCall[shift.ScheduleID][shift.UserID] {
+		if other.From.After(period.To) || period.From.After(other.To) {
+			merged = append(merged, other)
+			continue
+		}
+		if other.From.Before(period.From) {
+			period.From = other.From
+		}
+		if other.To.After(period.To) {
+			period.To = other.To
+		}
+	}
+	merged = append(merged, period)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].From.Before(merged[j].From) })
+	c.OnCall[shift.ScheduleID][shift.UserID] = merged
+}
+
+// onCallAt returns the user IDs on-call for each schedule ID at the given time, sorted.
+func (c *onCallCursor) onCallAt(at time.Time) map[string][]int {
+	onCall := make(map[string][]int, len(c.OnCall))
+	for scheduleID, periodsByUserID := range c.OnCall {
+		for userID, periods := range periodsByUserID {
+			if slices.ContainsFunc(periods, func(period onCallPeriod) bool { return period.covers(at) }) {
+				onCall[scheduleID] = append(onCall[scheduleID], userID)
+			}
+		}
+		slices.Sort(onCall[scheduleID])
+	}
+	return onCall
+}
+
+// covers reports whether the user is on-call at the given time during the period.
+func (p onCallPeriod) covers(at time.Time) bool {
+	return !p.From.After(at) && p.To.After(at)
+}
+
+// onCallDigests returns a digest of the user IDs on-call for each schedule ID, leaving out schedules without any.
+func onCallDigests(onCall map[string][]int) map[string]string {
+	digests := make(map[string]string, len(onCall))
+	for scheduleID, userIDs := range onCall {
+		if len(userIDs) > 0 {
+			digests[scheduleID] = onCallDigest(userIDs)
+		}
+	}
+	return digests
+}
+
+// onCallDigest returns a digest of the given user IDs, regardless of their order.
+func onCallDigest(userIDs []int) string {
+	sorted := slices.Sorted(slices.Values(userIDs))
+	hash := fnv.New64a()
+	for _, userID := range sorted {
+		_, _ = fmt.Fprintf(hash, "%d,", userID)
+	}
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// missingPrevious returns the IDs of the schedules whose users on-call as of the last poll weren't all found again
+// among the shifts covering its time, sorted.
+func (c *onCallCursor) missingPrevious() []string {
+	previous := c.onCallAt(c.PolledAt)
+	var scheduleIDs []string
+	for scheduleID, digest := range c.OnCallDigests {
+		if onCallDigest(previous[scheduleID]) != digest {
+			scheduleIDs = append(scheduleIDs, scheduleID)
+		}
+	}
+	sort.Strings(scheduleIDs)
+	return scheduleIDs
+}
+
+// changes returns a grant event for each time a user became on-call within the window of the current poll, and a
+// revoke event for each time a user stopped being on-call within it, in the order they happened for each schedule.
+func (c *onCallCursor) changes() []*v2.Event {
+	var events []*v2.Event
+	for _, scheduleID := range slices.Sorted(maps.Keys(c.OnCall)) {
+		schedule := &v2.Resource{Id: &v2.ResourceId{
+			ResourceType: scheduleResourceType.Id,
+			Resource:     scheduleID,
+		}}
+		var scheduleEvents []*v2.Event
+		for _, userID := range slices.Sorted(maps.Keys(c.OnCall[scheduleID])) {
+			for _, period := range c.OnCall[scheduleID][userID] {
+				if c.withinWindow(period.From) {
+					scheduleEvents = append(scheduleEvents, &v2.Event{
+						Id:         fmt.Sprintf("on-call:%s:%d:start:%s", scheduleID, userID, period.From.UTC().Format(time.RFC3339)),
+						OccurredAt: timestamppb.New(period.From),
+						Event: &v2.Event_GrantEvent{GrantEvent: &v2.GrantEvent{
+							Grant: grant.NewGrant(schedule, scheduleOnCallEntitlement, userResourceID(userID)),
+						}},
+					})
+				}
+				if c.withinWindow(period.To) {
+					scheduleEvents = append(scheduleEvents, &v2.Event{
+						Id:         fmt.Sprintf("on-call:%s:%d:end:%s", scheduleID, userID, period.To.UTC().Format(time.RFC3339)),
+						OccurredAt: timestamppb.New(period.To),
+						Event: &v2.Event_RevokeEvent{RevokeEvent: &v2.RevokeEvent{
+							Entitlement: entitlement.NewAssignmentEntitlement(schedule, scheduleOnCallEntitlement),
+							Principal:   &v2.Resource{Id: userResourceID(userID)},
+						}},
+					})
+				}
+			}
+		}
+		sort.SliceStable(scheduleEvents, func(i, j int) bool {
+			return scheduleEvents[i].OccurredAt.AsTime().Before(scheduleEvents[j].OccurredAt.AsTime())
+		})
+		events = append(events, scheduleEvents...)
+	}
+	return events
+}
+
+// withinWindow reports whether the given time is after the last poll and no later than the current one.
+func (c *onCallCursor) withinWindow(at time.Time) bool {
+	return at.After(c.PolledAt) && !at.After(c.Until)
+}
+
+// scheduleName returns the name of the schedule with the given ID.
+func (f *onCallFeed) scheduleName(ctx context.Context, scheduleID string) (string, error) {
+	schedule, err := f.client.GetSchedule(ctx, scheduleID)
+	if err != nil {
+		return "", err
+	}
+	return schedule.Attributes.Name, nil
+}
+
+func newOnCallFeed(client *client.Client, onCallWindow time.Duration, selection *syncSelection) *onCallFeed {
+	return &onCallFeed{
+		client:       client,
+		onCallWindow: onCallWindow,
+		selection:    selection,
+		now:          time.Now,
+	}
+}
@@ -0,0 +1,188 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serveShifts registers the endpoint listing the given shifts, filtered by the requested window, on the fake Rootly API.
+func serveShifts(t *testing.T, fake *fakeRootly, shifts []client.ScheduleShift) {
+	parse := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		require.NoError(t, err)
+		return parsed
+	}
+	fake.handle("GET /v1/shifts", func(writer http.ResponseWriter, request *http.Request) {
+		from := parse(request.URL.Query().Get("from"))
+		to := parse(request.URL.Query().Get("to"))
+		var matching []client.ScheduleShift
+		for _, shift := range shifts {
+			if !parse(shift.Attributes.StartsAt).After(to) && !parse(shift.Attributes.EndsAt).Before(from) {
+				matching = append(matching, shift)
+			}
+		}
+		resp := client.ScheduleShiftsResponse{}
+		resp.Data, resp.Links.Next = paginate(t, request, matching)
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+}
+
+// newTestShift returns a shift of the given user for the given schedule.
+func newTestShift(id string, scheduleID string, userID string, startsAt string, endsAt string) client.ScheduleShift {
+	return client.ScheduleShift{
+		ID:   id,
+		Type: "shifts",
+		Attributes: client.ScheduleShiftAttributes{
+			ScheduleID: scheduleID,
+			StartsAt:   startsAt,
+			EndsAt:     endsAt,
+		},
+		Relationships: client.ScheduleShiftRelationships{
+			User: client.SingleRelationship{Data: &client.ObjectWithoutAttributes{ID: userID, Type: "users"}},
+		},
+	}
+}
+
+// pollOnCallFeed returns a function polling the feed at the given time, starting at start, which pages through the
+// shifts one per page and returns the on-call changes.
+func pollOnCallFeed(t *testing.T, feed *onCallFeed, now *time.Time, start *timestamppb.Timestamp) func(at string) []string {
+	var cursor string
+	return func(at string) []string {
+		var err error
+		*now, err = time.Parse(time.RFC3339, at)
+		require.NoError(t, err)
+		var changes []string
+		for {
+			events, state, _, err := feed.ListEvents(context.Background(), start, &pagination.StreamToken{Size: 1, Cursor: cursor})
+			require.NoError(t, err)
+			for _, event := range events {
+				if grantEvent := event.GetGrantEvent(); grantEvent != nil {
+					changes = append(changes, "grant "+grantEvent.GetGrant().GetId()+" at "+event.OccurredAt.AsTime().Format(time.Kitchen))
+				}
+				if revokeEvent := event.GetRevokeEvent(); revokeEvent != nil {
+					changes = append(changes, "revoke "+revokeEvent.GetEntitlement().GetId()+":user:"+
+						revokeEvent.GetPrincipal().GetId().GetResource()+" at "+event.OccurredAt.AsTime().Format(time.Kitchen))
+				}
+			}
+			cursor = state.Cursor
+			if !state.HasMore {
+				return changes
+			}
+		}
+	}
+}
+
+func Test_onCallFeed_ListEventsAcrossPolls(t *testing.T) {
+	fake := newFakeRootly(t)
+	serveShifts(t, fake, []client.ScheduleShift{
+		newTestShift("shift-1", "primary-guid", "97487", "2025-04-10T08:00:00Z", "2025-04-10T12:30:00Z"),
+		newTestShift("override-1", "primary-guid", "96913", "2025-04-10T12:05:00Z", "2025-04-10T12:20:00Z"),
+		newTestShift("shift-2", "secondary-guid", "98001", "2025-04-10T12:15:00Z", "2025-04-10T14:00:00Z"),
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	feed := newOnCallFeed(rootlyClient, 0, nil)
+	var now time.Time
+	feed.now = func() time.Time { return now }
+	poll := pollOnCallFeed(t, feed, &now, timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)))
+
+	// the user already on-call at the start of the feed isn't reported
+	require.Equal(t, []string{
+		"grant schedule:primary-guid:on-call:user:96913 at 12:05PM",
+	}, poll("2025-04-10T12:10:00Z"))
+
+	require.Equal(t, []string{
+		"revoke schedule:primary-guid:on-call:user:96913 at 12:20PM",
+		"grant schedule:secondary-guid:on-call:user:98001 at 12:15PM",
+	}, poll("2025-04-10T12:25:00Z"))
+
+	require.Equal(t, []string{
+		"revoke schedule:primary-guid:on-call:user:97487 at 12:30PM",
+	}, poll("2025-04-10T12:40:00Z"))
+
+	require.Empty(t, poll("2025-04-10T12:50:00Z"))
+}
+
+func Test_onCallFeed_ListEventsWithinOnCallWindow(t *testing.T) {
+	fake := newFakeRootly(t)
+	serveShifts(t, fake, []client.ScheduleShift{
+		newTestShift("shift-1", "primary-guid", "96913", "2025-04-10T12:22:00Z", "2025-04-10T12:24:00Z"),
+		newTestShift("shift-2", "primary-guid", "97487", "2025-04-10T12:40:00Z", "2025-04-10T13:00:00Z"),
+		newTestShift("shift-3", "primary-guid", "97487", "2025-04-10T13:00:00Z", "2025-04-10T13:30:00Z"),
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	// users are on-call from 10 minutes before their shift starts, as for the on-call grants of a sync
+	feed := newOnCallFeed(rootlyClient, 10*time.Minute, nil)
+	var now time.Time
+	feed.now = func() time.Time { return now }
+	poll := pollOnCallFeed(t, feed, &now, timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)))
+
+	require.Empty(t, poll("2025-04-10T12:10:00Z"))
+
+	// a user on-call only between two polls is reported both ways
+	require.Equal(t, []string{
+		"grant schedule:primary-guid:on-call:user:96913 at 12:12PM",
+		"revoke schedule:primary-guid:on-call:user:96913 at 12:24PM",
+	}, poll("2025-04-10T12:25:00Z"))
+
+	require.Equal(t, []string{
+		"grant schedule:primary-guid:on-call:user:97487 at 12:30PM",
+	}, poll("2025-04-10T12:40:00Z"))
+
+	// back-to-back shifts don't revoke the user in between
+	require.Empty(t, poll("2025-04-10T13:10:00Z"))
+	require.Equal(t, []string{
+		"revoke schedule:primary-guid:on-call:user:97487 at 1:30PM",
+	}, poll("2025-04-10T13:35:00Z"))
+}
+
+func Test_onCallFeed_ListEventsFiltersSchedulesByName(t *testing.T) {
+	fake := newFakeRootly(t)
+	serveShifts(t, fake, []client.ScheduleShift{
+		newTestShift("shift-1", "primary-guid", "97487", "2025-04-10T08:00:00Z", "2025-04-10T12:30:00Z"),
+		newTestShift("shift-2", "primary-guid", "96913", "2025-04-10T12:05:00Z", "2025-04-10T14:00:00Z"),
+		newTestShift("shift-3", "sandbox-guid", "98001", "2025-04-10T12:05:00Z", "2025-04-10T14:00:00Z"),
+		newTestShift("shift-4", "deleted-guid", "98001", "2025-04-10T12:05:00Z", "2025-04-10T14:00:00Z"),
+	})
+	fake.respond(map[string]string{
+		"GET /v1/schedules/primary-guid": `{"data": {"id": "primary-guid", "type": "schedules", "attributes": {"name": "Primary"}}}`,
+		"GET /v1/schedules/sandbox-guid": `{"data": {"id": "sandbox-guid", "type": "schedules", "attributes": {"name": "Sandbox"}}}`,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, selection.setNameFilter(scheduleResourceType.Id, "", "^Sandbox$"))
+	feed := newOnCallFeed(rootlyClient, 0, selection)
+	feed.now = func() time.Time { return time.Date(2025, 4, 10, 12, 10, 0, 0, time.UTC) }
+	start := timestamppb.New(time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC))
+
+	events, state, _, err := feed.ListEvents(ctx, start, &pagination.StreamToken{Size: 10})
+	require.NoError(t, err)
+	require.False(t, state.HasMore)
+	require.Len(t, events, 1)
+	require.Equal(t, "schedule:primary-guid:on-call:user:96913", events[0].GetGrantEvent().GetGrant().GetId())
+	// each schedule is looked up once per poll
+	require.Equal(t, 1, fake.requestCount("/v1/schedules/primary-guid"))
+
+	// the cursor only keeps a digest of who's on-call for each synced schedule
+	cursor := &onCallCursor{}
+	require.NoError(t, json.Unmarshal([]byte(state.Cursor), cursor))
+	require.Equal(t, map[string]string{"primary-guid": onCallDigest([]int{96913, 97487})}, cursor.OnCallDigests)
+	require.Empty(t, cursor.OnCall)
+}
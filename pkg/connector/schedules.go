@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
+	rootlyv1 "github.com/conductorone/baton-rootly/pb/rootly/v1"
 	"github.com/conductorone/baton-rootly/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -25,6 +27,9 @@ const (
 type scheduleBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.Client
+	incremental  *incrementalSync
+	onCallWindow time.Duration
+	selection    *syncSelection
 }
 
 func (o *scheduleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -53,8 +58,8 @@ func (o *scheduleBuilder) List(ctx context.Context, parentResourceID *v2.Resourc
 		})
 	}
 
-	// fetch schedules from the Rootly API with pagination
-	schedules, token, err := o.client.GetSchedules(ctx, bag.PageToken())
+	// fetch schedules from the Rootly API with pagination, narrowed server-side by the include pattern when possible
+	schedules, token, err := o.client.GetSchedules(ctx, bag.PageToken(), o.selection.nameListOptions(o.resourceType.Id)...)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -62,13 +67,12 @@ func (o *scheduleBuilder) List(ctx context.Context, parentResourceID *v2.Resourc
 	// create schedule resources using the SDK
 	var resources []*v2.Resource
 	for _, schedule := range schedules {
-		scheduleResource, err := sdkResource.NewGroupResource(
-			schedule.Attributes.Name,
-			o.resourceType,
-			schedule.ID,
-			getScheduleTraitOptions(schedule),
-			sdkResource.WithParentResourceID(parentResourceID),
-		)
+		// skip schedules filtered out by name, so that neither they nor their grants are synced
+		if !o.selection.syncsResourceName(o.resourceType.Id, schedule.Attributes.Name) {
+			continue
+		}
+
+		scheduleResource, err := newScheduleResource(schedule, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -82,7 +86,39 @@ func (o *scheduleBuilder) List(ctx context.Context, parentResourceID *v2.Resourc
 		return nil, "", nil, err
 	}
 
-	return resources, nextPage, nil, nil
+	return resources, nextPage, withRateLimit(nil, o.client), nil
+}
+
+// Get returns a single schedule, e.g. for a targeted sync after its rotations changed, or nil if it no longer exists or
+// is filtered out by name.
+func (o *scheduleBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, parentResourceID *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	schedule, err := o.client.GetSchedule(ctx, resourceID.Resource)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !o.selection.syncsResourceName(o.resourceType.Id, schedule.Attributes.Name) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+
+	scheduleResource, err := newScheduleResource(*schedule, parentResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scheduleResource, withRateLimit(nil, o.client), nil
+}
+
+// newScheduleResource creates a schedule resource using the SDK.
+func newScheduleResource(schedule client.Schedule, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return sdkResource.NewGroupResource(
+		schedule.Attributes.Name,
+		scheduleResourceType,
+		schedule.ID,
+		getScheduleTraitOptions(schedule),
+		sdkResource.WithParentResourceID(parentResourceID),
+	)
 }
 
 // getScheduleTraitOptions returns a list of GroupTraitOption's based on the available fields for a Rootly schedule.
@@ -118,7 +154,7 @@ func (o *scheduleBuilder) Entitlements(
 		zap.String("resource.Id.Resource", resource.Id.Resource),
 	)
 
-	return []*v2.Entitlement{
+	return o.selection.filterEntitlements(o.resourceType.Id, []*v2.Entitlement{
 		entitlement.NewAssignmentEntitlement(
 			resource,
 			scheduleOwnerEntitlement,
@@ -151,7 +187,7 @@ func (o *scheduleBuilder) Entitlements(
 				fmt.Sprintf("Is on-call member of the %s schedule in Rootly", resource.DisplayName),
 			),
 		),
-	}, "", nil, nil
+	}), "", nil, nil
 }
 
 // Grants for each schedule include checking current owners, current members, and current on-call members.
@@ -173,109 +209,103 @@ func (o *scheduleBuilder) Grants(
 			ResourceID:     resource.Id.Resource,
 		})
 	}
-	// create a new resource type ID for tracking schedule rotation pagination, only used within Grants
-	const scheduleRotationResourceTypeID = "schedule_rotation"
 
 	var grants []*v2.Grant
-	switch bag.ResourceTypeID() {
-	case scheduleResourceType.Id:
-		scheduleID := bag.ResourceID()
-		// only handle the schedule owners and on-call members once, ie on the first iteration
-		if bag.PageToken() == "" {
+	var annos annotations.Annotations
+	scheduleID := bag.ResourceID()
+	// only handle the schedule owners and on-call members once, ie on the first iteration
+	if bag.PageToken() == "" {
+		if o.selection.syncsEntitlement(o.resourceType.Id, scheduleOwnerEntitlement) {
 			// fetch schedule owners from the Rootly API
 			ownerUserID, ownerTeamIDs, err := o.client.GetScheduleOwnerIDs(ctx, scheduleID)
+			if client.IsNotFound(err) {
+				return deletedResourceGrants(ctx, o.client, resource, err)
+			}
 			if err != nil {
 				return nil, "", nil, err
 			}
-			// add a grant for the owner user
-			if ownerUserID != nil {
-				grants = append(grants, grant.NewGrant(
-					resource,
-					scheduleOwnerEntitlement,
-					&v2.ResourceId{
-						ResourceType: userResourceType.Id,
-						Resource:     strconv.Itoa(*ownerUserID),
-					},
-				))
-			}
-			// add grants for the owner team(s), and the users nested within
-			for _, ownerTeamID := range ownerTeamIDs {
-				grants = append(grants, grant.NewGrant(
-					resource,
-					scheduleOwnerEntitlement,
-					&v2.ResourceId{
-						ResourceType: teamResourceType.Id,
-						Resource:     ownerTeamID,
-					},
-					grant.WithAnnotation(&v2.GrantExpandable{
-						EntitlementIds: []string{
-							fmt.Sprintf("team:%s:%s", ownerTeamID, teamMemberEntitlement),
-							fmt.Sprintf("team:%s:%s", ownerTeamID, teamAdminEntitlement),
-						},
-					}),
-				))
-			}
+			grants = append(grants, o.newScheduleOwnerGrants(resource, ownerUserID, ownerTeamIDs)...)
+		}
 
-			// fetch schedule on-call members from the Rootly API
-			onCallUserIDs, err := o.client.ListOnCallUsers(ctx, scheduleID)
+		if o.selection.syncsEntitlement(o.resourceType.Id, scheduleOnCallEntitlement) {
+			// fetch schedule on-call shifts within the on-call window from the Rootly API
+			onCallShifts, err := o.client.ListAllOnCallShifts(ctx, scheduleID, o.onCallWindow)
 			if err != nil {
 				return nil, "", nil, err
 			}
 			// add grants for schedule on-call members
-			for _, onCallUserID := range onCallUserIDs {
-				grants = append(grants, grant.NewGrant(
-					resource,
-					scheduleOnCallEntitlement,
-					&v2.ResourceId{
-						ResourceType: userResourceType.Id,
-						Resource:     strconv.Itoa(onCallUserID),
-					},
-				))
-			}
+			grants = append(grants, newScheduleOnCallGrants(resource, onCallShifts)...)
 		}
 
-		// fetching schedule members is more complex since it entails nested paginated API calls:
-		// 	1) this iteration fetch schedule rotations from the Rootly API and push each rotation to the bag.
-		// 	   if there are more rotation pages, also push the next page token to the bag for a future iteration.
-		// 	2) next iteration(s) fetch all the members for a rotation, handled within the other switch case.
-		rotationIDs, nextPage, err := o.client.ListScheduleRotations(ctx, scheduleID, bag.PageToken())
-		if err != nil {
-			return nil, "", nil, err
+		if !o.selection.syncsEntitlement(o.resourceType.Id, scheduleMemberEntitlement) {
+			return grants, "", withRateLimit(nil, o.client), nil
 		}
-		bag.Pop()
-		if nextPage != "" {
-			// there are more schedule rotations to fetch for this schedule
-			bag.Push(pagination.PageState{
-				ResourceTypeID: scheduleResourceType.Id,
-				ResourceID:     scheduleID,
-				Token:          nextPage,
-			})
+
+		// members are the most expensive grants to fetch, so with incremental sync they're carried over
+		// from the previous sync when neither the schedule nor its rotations have changed since
+		if o.incremental.reusesGrants() {
+			// the rotations are read bypassing the GET cache, which may hold them from a previous sync
+			rotations, err := o.client.Uncached().ListAllScheduleRotations(ctx, scheduleID)
+			if client.IsNotFound(err) {
+				return deletedResourceGrants(ctx, o.client, resource, err)
+			}
+			if err != nil {
+				return nil, "", nil, err
+			}
+			memberEntitlementID := entitlement.NewEntitlementID(resource, scheduleMemberEntitlement)
+			if o.incremental.canReuseGrants(resource, memberEntitlementID, rotations) {
+				annos.Update(&v2.ETagMatch{EntitlementId: memberEntitlementID})
+				return grants, "", withRateLimit(annos, o.client), nil
+			}
+			annos.Update(o.incremental.eTag(memberEntitlementID, rotations))
 		}
-		for _, rotationID := range rotationIDs {
-			bag.Push(pagination.PageState{
-				ResourceTypeID: scheduleRotationResourceTypeID,
-				ResourceID:     rotationID,
-			})
+	}
+
+	// fetching schedule members is more complex since it entails nested paginated API calls:
+	// 	1) each iteration fetches a page of schedule rotations from the Rootly API, including their rotation users,
+	// 	   and adds grants for the members of each rotation whose included users are complete.
+	// 	   if there are more rotation pages, the next page token is pushed to the bag for a future iteration.
+	// 	2) the members of the rotations whose included users were truncated are fetched concurrently by the client.
+	rotations, nextPage, err := o.client.ListScheduleRotationsWithUsers(ctx, scheduleID, bag.PageToken())
+	if client.IsNotFound(err) {
+		return deletedResourceGrants(ctx, o.client, resource, err)
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+	bag.Pop()
+	if nextPage != "" {
+		// there are more schedule rotations to fetch for this schedule
+		bag.Push(pagination.PageState{
+			ResourceTypeID: scheduleResourceType.Id,
+			ResourceID:     scheduleID,
+			Token:          nextPage,
+		})
+	}
+	var truncatedRotationIDs []string
+	for _, rotation := range rotations {
+		if rotation.Complete {
+			grants = append(grants, newScheduleMemberGrants(resource, rotation.UserIDs)...)
+			continue
 		}
-	case scheduleRotationResourceTypeID:
-		// fetch all members for the schedule rotation from the Rootly API
-		rotationID := bag.ResourceID()
-		memberUserIDs, err := o.client.ListAllScheduleRotationUsers(ctx, rotationID)
-		if err != nil {
-			return nil, "", nil, err
+		truncatedRotationIDs = append(truncatedRotationIDs, rotation.RotationID)
+	}
+	truncatedRotations, err := o.client.ListAllScheduleRotationUsersConcurrently(ctx, truncatedRotationIDs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	for _, rotation := range truncatedRotations {
+		if rotation.Deleted {
+			// the rotation was deleted since the rotations were listed, so it has no members anymore
+			annos = withWarning(ctx, annos, &rootlyv1.Warning{
+				Message:        fmt.Sprintf("schedule rotation %s was deleted during the sync, its members were skipped", rotation.RotationID),
+				ResourceTypeId: resource.Id.ResourceType,
+				ResourceId:     resource.Id.Resource,
+			})
+			continue
 		}
-		bag.Pop()
 		// add grants for these members
-		for _, memberUserID := range memberUserIDs {
-			grants = append(grants, grant.NewGrant(
-				resource,
-				scheduleMemberEntitlement,
-				&v2.ResourceId{
-					ResourceType: userResourceType.Id,
-					Resource:     strconv.Itoa(memberUserID),
-				},
-			))
-		}
+		grants = append(grants, newScheduleMemberGrants(resource, rotation.UserIDs)...)
 	}
 
 	pageToken, err := bag.Marshal()
@@ -283,12 +313,116 @@ func (o *scheduleBuilder) Grants(
 		return nil, "", nil, err
 	}
 
-	return grants, pageToken, nil, nil
+	return grants, pageToken, withRateLimit(annos, o.client), nil
+}
+
+// newScheduleOwnerGrants returns the owner grants of a schedule for its owner user and teams. Owner teams are only
+// granted when teams are synced, and expand to the synced team entitlements.
+func (o *scheduleBuilder) newScheduleOwnerGrants(resource *v2.Resource, ownerUserID *int, ownerTeamIDs []string) []*v2.Grant {
+	var grants []*v2.Grant
+	// add a grant for the owner user
+	if ownerUserID != nil {
+		grants = append(grants, grant.NewGrant(
+			resource,
+			scheduleOwnerEntitlement,
+			&v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     strconv.Itoa(*ownerUserID),
+			},
+		))
+	}
+	if !o.selection.syncsResourceType(teamResourceType.Id) {
+		return grants
+	}
+
+	// add grants for the owner team(s), and the users nested within
+	for _, ownerTeamID := range ownerTeamIDs {
+		var expandable []string
+		for _, teamEntitlement := range []string{teamMemberEntitlement, teamAdminEntitlement} {
+			if o.selection.syncsEntitlement(teamResourceType.Id, teamEntitlement) {
+				expandable = append(expandable, fmt.Sprintf("team:%s:%s", ownerTeamID, teamEntitlement))
+			}
+		}
+		var grantOptions []grant.GrantOption
+		if len(expandable) > 0 {
+			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: expandable,
+			}))
+		}
+		grants = append(grants, grant.NewGrant(
+			resource,
+			scheduleOwnerEntitlement,
+			&v2.ResourceId{
+				ResourceType: teamResourceType.Id,
+				Resource:     ownerTeamID,
+			},
+			grantOptions...,
+		))
+	}
+	return grants
+}
+
+// newScheduleOnCallGrants returns one on-call grant per user with shifts in the on-call window,
+// with the start and end times of those shifts as grant metadata.
+func newScheduleOnCallGrants(resource *v2.Resource, shifts []client.OnCallShift) []*v2.Grant {
+	var userIDs []int
+	shiftsByUserID := make(map[int][]interface{})
+	for _, shift := range shifts {
+		if _, ok := shiftsByUserID[shift.UserID]; !ok {
+			userIDs = append(userIDs, shift.UserID)
+		}
+		shiftsByUserID[shift.UserID] = append(shiftsByUserID[shift.UserID], map[string]interface{}{
+			"shift_id":    shift.ShiftID,
+			"starts_at":   shift.StartsAt,
+			"ends_at":     shift.EndsAt,
+			"is_override": shift.IsOverride,
+		})
+	}
+
+	var grants []*v2.Grant
+	for _, userID := range userIDs {
+		grants = append(grants, grant.NewGrant(
+			resource,
+			scheduleOnCallEntitlement,
+			&v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     strconv.Itoa(userID),
+			},
+			grant.WithGrantMetadata(map[string]interface{}{
+				"shifts": shiftsByUserID[userID],
+			}),
+		))
+	}
+	return grants
+}
+
+// newScheduleMemberGrants returns the member grants of a schedule for the given rotation member user IDs.
+func newScheduleMemberGrants(resource *v2.Resource, memberUserIDs []int) []*v2.Grant {
+	var grants []*v2.Grant
+	for _, memberUserID := range memberUserIDs {
+		grants = append(grants, grant.NewGrant(
+			resource,
+			scheduleMemberEntitlement,
+			&v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     strconv.Itoa(memberUserID),
+			},
+		))
+	}
+	return grants
 }
 
-func newScheduleBuilder(client *client.Client) *scheduleBuilder {
+func newScheduleBuilder(
+	client *client.Client,
+	incremental *incrementalSync,
+	onCallWindow time.Duration,
+	selection *syncSelection,
+) *scheduleBuilder {
 	return &scheduleBuilder{
 		client:       client,
 		resourceType: scheduleResourceType,
+		incremental:  incremental,
+		onCallWindow: onCallWindow,
+		selection:    selection,
 	}
 }
@@ -0,0 +1,356 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	scheduleGetResult = `{
+    "data": {
+        "id": "test-schedule-guid",
+        "type": "schedules",
+        "attributes": {
+            "name": "Production Oncall",
+            "owner_user_id": 96913,
+            "owner_group_ids": ["sre-team-guid"],
+            "created_at": "2025-04-07T08:00:54.675-07:00",
+            "updated_at": "2025-04-10T16:25:18.032-07:00"
+        }
+    }
+}`
+	scheduleShiftsResult = `{
+    "data": [
+        {
+            "id": "test-shift-guid-1",
+            "type": "shifts",
+            "attributes": {
+                "starts_at": "2025-04-09T12:00:00.000-07:00",
+                "ends_at": "2025-04-10T12:00:00.000-07:00",
+                "is_override": false
+            },
+            "relationships": {
+                "user": {"data": {"id": "97487", "type": "users"}}
+            }
+        },
+        {
+            "id": "test-shift-guid-2",
+            "type": "shifts",
+            "attributes": {
+                "starts_at": "2025-04-10T12:00:00.000-07:00",
+                "ends_at": "2025-04-10T18:00:00.000-07:00",
+                "is_override": true
+            },
+            "relationships": {
+                "user": {"data": {"id": "97487", "type": "users"}}
+            }
+        }
+    ],
+    "included": [
+        {"id": "97487", "type": "users"}
+    ],
+    "links": {
+        "next": null
+    }
+}`
+	scheduleRotationsWithUsersResult = `{
+    "data": [
+        {
+            "id": "test-weekday-rotation-guid",
+            "type": "schedule_rotations",
+            "attributes": {"updated_at": "2025-04-10T08:00:00.000-07:00"},
+            "relationships": {
+                "schedule_rotation_users": {
+                    "data": [
+                        {"id": "test-rotation-user-guid-1", "type": "schedule_rotation_users"},
+                        {"id": "test-rotation-user-guid-2", "type": "schedule_rotation_users"}
+                    ]
+                }
+            }
+        },
+        {
+            "id": "test-weekend-rotation-guid",
+            "type": "schedule_rotations",
+            "attributes": {"updated_at": "2025-04-10T08:00:00.000-07:00"},
+            "relationships": {
+                "schedule_rotation_users": {
+                    "data": [
+                        {"id": "test-rotation-user-guid-3", "type": "schedule_rotation_users"},
+                        {"id": "test-rotation-user-guid-4", "type": "schedule_rotation_users"}
+                    ]
+                }
+            }
+        }
+    ],
+    "included": [
+        {"id": "test-rotation-user-guid-1", "type": "schedule_rotation_users", "attributes": {"user_id": 96913}},
+        {"id": "test-rotation-user-guid-2", "type": "schedule_rotation_users", "attributes": {"user_id": 97487}},
+        {"id": "test-rotation-user-guid-3", "type": "schedule_rotation_users", "attributes": {"user_id": 96913}}
+    ],
+    "links": {
+        "next": null
+    }
+}`
+	weekendRotationUsersResult = `{
+    "data": [
+        {"id": "test-rotation-user-guid-3", "type": "schedule_rotation_users", "attributes": {"user_id": 96913}},
+        {"id": "test-rotation-user-guid-4", "type": "schedule_rotation_users", "attributes": {"user_id": 98001}}
+    ],
+    "links": {
+        "next": null
+    }
+}`
+)
+
+// newTestScheduleResource returns the schedule resource as listed by scheduleBuilder.List.
+func newTestScheduleResource(t *testing.T) *v2.Resource {
+	resource, err := sdkResource.NewGroupResource(
+		"Production Oncall",
+		scheduleResourceType,
+		"test-schedule-guid",
+		getScheduleTraitOptions(client.Schedule{
+			ID: "test-schedule-guid",
+			Attributes: client.ScheduleAttributes{
+				Name:      "Production Oncall",
+				CreatedAt: "2025-04-07T08:00:54.675-07:00",
+				UpdatedAt: "2025-04-10T16:25:18.032-07:00",
+			},
+		}),
+	)
+	require.NoError(t, err)
+	return resource
+}
+
+// listAllGrants calls Grants until there are no more pages, returning all the grants.
+func listAllGrants(ctx context.Context, t *testing.T, builder connectorbuilder.ResourceSyncer, resource *v2.Resource) []*v2.Grant {
+	var allGrants []*v2.Grant
+	pToken := &pagination.Token{}
+	for {
+		grants, nextPage, _, err := builder.Grants(ctx, resource, pToken)
+		require.NoError(t, err)
+		allGrants = append(allGrants, grants...)
+		if nextPage == "" {
+			return allGrants
+		}
+		pToken = &pagination.Token{Token: nextPage}
+	}
+}
+
+// grantIDs returns the IDs of the given grants.
+func grantIDs(grants []*v2.Grant) []string {
+	var ids []string
+	for _, g := range grants {
+		ids = append(ids, g.Id)
+	}
+	return ids
+}
+
+func Test_scheduleBuilder_GrantsWithIncludedRotationUsers(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/schedules/test-schedule-guid": scheduleGetResult,
+		"/v1/shifts":                       scheduleShiftsResult,
+		"/v1/schedules/test-schedule-guid/schedule_rotations":                       scheduleRotationsWithUsersResult,
+		"/v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users": weekendRotationUsersResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	builder := newScheduleBuilder(rootlyClient, newIncrementalSync(false, 0), client.DefaultOnCallWindow, nil)
+
+	grants := listAllGrants(ctx, t, builder, newTestScheduleResource(t))
+	require.ElementsMatch(t, []string{
+		"schedule:test-schedule-guid:owner:user:96913",
+		"schedule:test-schedule-guid:owner:team:sre-team-guid",
+		"schedule:test-schedule-guid:on-call:user:97487",
+		// weekday rotation, from the included rotation users
+		"schedule:test-schedule-guid:member:user:96913",
+		"schedule:test-schedule-guid:member:user:97487",
+		// weekend rotation, whose included rotation users were truncated
+		"schedule:test-schedule-guid:member:user:96913",
+		"schedule:test-schedule-guid:member:user:98001",
+	}, grantIDs(grants))
+
+	require.Equal(t, 1, fake.requestCount("/v1/schedules/test-schedule-guid/schedule_rotations"))
+	require.Equal(t, 0, fake.requestCount("/v1/schedule_rotations/test-weekday-rotation-guid/schedule_rotation_users"))
+	require.Equal(t, 1, fake.requestCount("/v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users"))
+}
+
+func Test_scheduleBuilder_GrantsReuseUnchangedMembers(t *testing.T) {
+	var mu sync.Mutex
+	rotations := scheduleRotationsWithUsersResult
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"GET /v1/schedules/test-schedule-guid": scheduleGetResult,
+		"GET /v1/shifts":                       scheduleShiftsResult,
+		"GET /v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users": weekendRotationUsersResult,
+	})
+	fake.handle("GET /v1/schedules/test-schedule-guid/schedule_rotations", func(writer http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = writer.Write([]byte(rotations))
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	incremental := newIncrementalSync(true, 0)
+	incremental.now = func() time.Time { return time.Date(2025, 4, 11, 12, 0, 0, 0, time.UTC) }
+	builder := newScheduleBuilder(rootlyClient, incremental, client.DefaultOnCallWindow, nil)
+
+	// syncGrants returns the member grants of the schedule, carrying the ETag of the previous sync if any, along with the
+	// annotations of the grants
+	syncGrants := func(prevETag *v2.ETag) ([]string, annotations.Annotations) {
+		resource := newTestScheduleResource(t)
+		if prevETag != nil {
+			resourceAnnos := annotations.Annotations(resource.Annotations)
+			resourceAnnos.Update(prevETag)
+			resource.Annotations = resourceAnnos
+		}
+		var memberGrantIDs []string
+		var allAnnos annotations.Annotations
+		pToken := &pagination.Token{}
+		for {
+			grants, nextPage, annos, err := builder.Grants(ctx, resource, pToken)
+			require.NoError(t, err)
+			for _, id := range grantIDs(grants) {
+				if strings.Contains(id, ":member:") {
+					memberGrantIDs = append(memberGrantIDs, id)
+				}
+			}
+			allAnnos = append(allAnnos, annos...)
+			if nextPage == "" {
+				return memberGrantIDs, allAnnos
+			}
+			pToken = &pagination.Token{Token: nextPage}
+		}
+	}
+
+	memberGrantIDs, annos := syncGrants(nil)
+	require.Len(t, memberGrantIDs, 4)
+	eTag := &v2.ETag{}
+	ok, err := annos.Pick(eTag)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1, fake.requestCount("/v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users"))
+
+	// the rotations haven't changed, so the members of the previous sync are reused
+	memberGrantIDs, annos = syncGrants(eTag)
+	require.Empty(t, memberGrantIDs)
+	require.True(t, annos.Contains(&v2.ETagMatch{}))
+	require.Equal(t, 1, fake.requestCount("/v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users"))
+
+	// a rotation updated since, e.g. because its users changed, fetches the members again
+	mu.Lock()
+	rotations = strings.Replace(rotations, "2025-04-10T08:00:00.000-07:00", "2025-04-11T11:58:00.000Z", 1)
+	mu.Unlock()
+	memberGrantIDs, annos = syncGrants(eTag)
+	require.Len(t, memberGrantIDs, 4)
+	require.False(t, annos.Contains(&v2.ETagMatch{}))
+}
+
+func Test_newScheduleOnCallGrants(t *testing.T) {
+	resource := newTestScheduleResource(t)
+	grants := newScheduleOnCallGrants(resource, []client.OnCallShift{
+		{ShiftID: "shift-1", UserID: 97487, StartsAt: "2025-04-09T12:00:00Z", EndsAt: "2025-04-10T12:00:00Z"},
+		{ShiftID: "shift-2", UserID: 96913, StartsAt: "2025-04-10T12:00:00Z", EndsAt: "2025-04-11T12:00:00Z"},
+		{ShiftID: "shift-3", UserID: 97487, StartsAt: "2025-04-11T12:00:00Z", EndsAt: "2025-04-11T18:00:00Z", IsOverride: true},
+	})
+	require.Equal(t, []string{
+		"schedule:test-schedule-guid:on-call:user:97487",
+		"schedule:test-schedule-guid:on-call:user:96913",
+	}, grantIDs(grants))
+
+	grantAnnos := annotations.Annotations(grants[0].Annotations)
+	metadata := &v2.GrantMetadata{}
+	ok, err := grantAnnos.Pick(metadata)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{
+		"shifts": []interface{}{
+			map[string]interface{}{
+				"shift_id":    "shift-1",
+				"starts_at":   "2025-04-09T12:00:00Z",
+				"ends_at":     "2025-04-10T12:00:00Z",
+				"is_override": false,
+			},
+			map[string]interface{}{
+				"shift_id":    "shift-3",
+				"starts_at":   "2025-04-11T12:00:00Z",
+				"ends_at":     "2025-04-11T18:00:00Z",
+				"is_override": true,
+			},
+		},
+	}, metadata.Metadata.AsMap())
+}
+
+func Test_scheduleBuilder_GrantsSkipDeletedSchedule(t *testing.T) {
+	// the schedule was deleted between List and Grants, so its endpoints respond 404
+	fake := newFakeRootly(t)
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	builder := newScheduleBuilder(rootlyClient, newIncrementalSync(false, 0), client.DefaultOnCallWindow, nil)
+
+	grants, nextPage, annos, err := builder.Grants(ctx, newTestScheduleResource(t), &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, nextPage)
+	require.Empty(t, grants)
+	require.Equal(t, []string{"schedule test-schedule-guid"}, warnings(t, annos))
+	require.Equal(t, 1, fake.requestCount("/v1/schedules/test-schedule-guid"))
+	require.Equal(t, 0, fake.requestCount("/v1/shifts"))
+}
+
+func Test_scheduleBuilder_GrantsSkipDeletedRotation(t *testing.T) {
+	// the weekend rotation was deleted after the rotations were listed, so its users endpoint responds 404
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/schedules/test-schedule-guid": scheduleGetResult,
+		"/v1/shifts":                       scheduleShiftsResult,
+		"/v1/schedules/test-schedule-guid/schedule_rotations": scheduleRotationsWithUsersResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	builder := newScheduleBuilder(rootlyClient, newIncrementalSync(false, 0), client.DefaultOnCallWindow, nil)
+	resource := newTestScheduleResource(t)
+
+	var grants []*v2.Grant
+	var allWarnings []string
+	pToken := &pagination.Token{}
+	for {
+		pageGrants, nextPage, annos, err := builder.Grants(ctx, resource, pToken)
+		require.NoError(t, err)
+		grants = append(grants, pageGrants...)
+		allWarnings = append(allWarnings, warnings(t, annos)...)
+		if nextPage == "" {
+			break
+		}
+		pToken = &pagination.Token{Token: nextPage}
+	}
+
+	require.ElementsMatch(t, []string{
+		"schedule:test-schedule-guid:owner:user:96913",
+		"schedule:test-schedule-guid:owner:team:sre-team-guid",
+		"schedule:test-schedule-guid:on-call:user:97487",
+		"schedule:test-schedule-guid:member:user:96913",
+		"schedule:test-schedule-guid:member:user:97487",
+	}, grantIDs(grants))
+	require.Equal(t, []string{"schedule test-schedule-guid"}, allWarnings)
+	require.Equal(t, 1, fake.requestCount("/v1/schedule_rotations/test-weekend-rotation-guid/schedule_rotation_users"))
+}
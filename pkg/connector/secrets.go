@@ -52,13 +52,7 @@ func (o *secretBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 	// create secret resources using the SDK
 	var resources []*v2.Resource
 	for _, secret := range secrets {
-		secretResource, err := sdkResource.NewSecretResource(
-			secret.Attributes.Name,
-			o.resourceType,
-			secret.ID,
-			getSecretTraitOptions(secret),
-			sdkResource.WithParentResourceID(parentResourceID),
-		)
+		secretResource, err := newSecretResource(secret, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -72,7 +66,35 @@ func (o *secretBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		return nil, "", nil, err
 	}
 
-	return resources, nextPage, nil, nil
+	return resources, nextPage, withRateLimit(nil, o.client), nil
+}
+
+// Get returns a single secret, e.g. for a targeted sync after the secret changed, or nil if it no longer exists.
+func (o *secretBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, parentResourceID *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	secret, err := o.client.GetSecret(ctx, resourceID.Resource)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secretResource, err := newSecretResource(*secret, parentResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secretResource, withRateLimit(nil, o.client), nil
+}
+
+// newSecretResource creates a secret resource using the SDK.
+func newSecretResource(secret client.Secret, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return sdkResource.NewSecretResource(
+		secret.Attributes.Name,
+		secretResourceType,
+		secret.ID,
+		getSecretTraitOptions(secret),
+		sdkResource.WithParentResourceID(parentResourceID),
+	)
 }
 
 // getSecretTraitOptions returns a list of SecretTraitOption's based on the available fields for a Rootly secret.
@@ -0,0 +1,211 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// entitlementsByResourceType lists the entitlement names of each resource type that has entitlements.
+var entitlementsByResourceType = map[string][]string{
+	teamResourceType.Id:     {teamAdminEntitlement, teamMemberEntitlement},
+	scheduleResourceType.Id: {scheduleOwnerEntitlement, scheduleMemberEntitlement, scheduleOnCallEntitlement},
+}
+
+// syncSelection holds which resource types and entitlements are synced, so that tenants only pay for the data
+// they review. Everything is synced by default.
+type syncSelection struct {
+	skippedResourceTypes map[string]bool
+	// skippedEntitlements is keyed by "<resource type ID>:<entitlement name>", e.g. "schedule:on-call"
+	skippedEntitlements map[string]bool
+	// nameFilters is keyed by resource type ID
+	nameFilters map[string]*nameFilter
+}
+
+// nameFilter selects resources by display name: a resource is synced when its name matches the include pattern,
+// if any, and doesn't match the exclude pattern, if any.
+type nameFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// newSyncSelection validates the resource type IDs and entitlements to skip, the latter formatted as
+// "<resource type ID>:<entitlement name>".
+func newSyncSelection(skipResourceTypes []string, skipEntitlements []string) (*syncSelection, error) {
+	s := &syncSelection{
+		skippedResourceTypes: make(map[string]bool),
+		skippedEntitlements:  make(map[string]bool),
+		nameFilters:          make(map[string]*nameFilter),
+	}
+
+	for _, resourceTypeID := range skipResourceTypes {
+		resourceTypeID = strings.TrimSpace(resourceTypeID)
+		switch resourceTypeID {
+		case userResourceType.Id:
+			return nil, fmt.Errorf("the %s resource type can't be skipped, since it's the principal of every grant", resourceTypeID)
+		case teamResourceType.Id, secretResourceType.Id, scheduleResourceType.Id:
+			s.skippedResourceTypes[resourceTypeID] = true
+		default:
+			return nil, fmt.Errorf("unknown resource type %q to skip", resourceTypeID)
+		}
+	}
+
+	for _, skipEntitlement := range skipEntitlements {
+		skipEntitlement = strings.TrimSpace(skipEntitlement)
+		resourceTypeID, name, ok := strings.Cut(skipEntitlement, ":")
+		if !ok || !containsString(entitlementsByResourceType[resourceTypeID], name) {
+			return nil, fmt.Errorf("unknown entitlement %q to skip, expected e.g. %s:%s", skipEntitlement, scheduleResourceType.Id, scheduleOnCallEntitlement)
+		}
+		s.skippedEntitlements[skipEntitlement] = true
+	}
+
+	return s, nil
+}
+
+// setNameFilter compiles the include and exclude patterns filtering the resources of a type by name. Empty patterns
+// are ignored.
+func (s *syncSelection) setNameFilter(resourceTypeID string, include string, exclude string) error {
+	f := &nameFilter{}
+	var err error
+	if include != "" {
+		f.include, err = regexp.Compile(include)
+		if err != nil {
+			return fmt.Errorf("invalid %s include pattern %q: %w", resourceTypeID, include, err)
+		}
+	}
+	if exclude != "" {
+		f.exclude, err = regexp.Compile(exclude)
+		if err != nil {
+			return fmt.Errorf("invalid %s exclude pattern %q: %w", resourceTypeID, exclude, err)
+		}
+	}
+	if f.include == nil && f.exclude == nil {
+		delete(s.nameFilters, resourceTypeID)
+		return nil
+	}
+	s.nameFilters[resourceTypeID] = f
+	return nil
+}
+
+// syncsResourceName reports whether a resource of the given type and name is synced.
+func (s *syncSelection) syncsResourceName(resourceTypeID string, name string) bool {
+	if s == nil || s.nameFilters[resourceTypeID] == nil {
+		return true
+	}
+	f := s.nameFilters[resourceTypeID]
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// hasNameFilter reports whether resources of the given type are filtered by name, in which case the name of a
+// resource referenced only by ID must be looked up to tell whether it's synced.
+func (s *syncSelection) hasNameFilter(resourceTypeID string) bool {
+	return s != nil && s.nameFilters[resourceTypeID] != nil
+}
+
+// syncsResourceID reports whether the resource of the given type and ID is synced, for resources only referenced by
+// ID, e.g. by an event. When resources of the type are filtered by name, its name is looked up with getName, and a
+// deleted resource isn't synced. The resources already looked up are kept in synced.
+func (s *syncSelection) syncsResourceID(
+	ctx context.Context,
+	resourceTypeID string,
+	resourceID string,
+	synced map[string]bool,
+	getName func(ctx context.Context, resourceID string) (string, error),
+) (bool, error) {
+	if !s.hasNameFilter(resourceTypeID) {
+		return true, nil
+	}
+	if ok, found := synced[resourceID]; found {
+		return ok, nil
+	}
+	name, err := getName(ctx, resourceID)
+	if client.IsNotFound(err) {
+		synced[resourceID] = false
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	synced[resourceID] = s.syncsResourceName(resourceTypeID, name)
+	return synced[resourceID], nil
+}
+
+// nameListOptions returns the list options narrowing a list request server-side to the resources that may match the
+// include pattern of a type: an exact name filter when the pattern only matches a literal name, or a search for the
+// literal text every match starts with. Patterns without such a literal prefix, e.g. case-insensitive ones or
+// alternations, don't narrow the request. Results still need to be filtered with syncsResourceName.
+func (s *syncSelection) nameListOptions(resourceTypeID string) []client.ListOption {
+	if s == nil || s.nameFilters[resourceTypeID] == nil || s.nameFilters[resourceTypeID].include == nil {
+		return nil
+	}
+	include := s.nameFilters[resourceTypeID].include
+	prefix, _ := include.LiteralPrefix()
+	if prefix == "" {
+		return nil
+	}
+	if include.String() == "^"+regexp.QuoteMeta(prefix)+"$" {
+		return []client.ListOption{client.WithName(prefix)}
+	}
+	return []client.ListOption{client.WithSearch(prefix)}
+}
+
+// syncsResourceType reports whether resources of the given type are synced.
+func (s *syncSelection) syncsResourceType(resourceTypeID string) bool {
+	if s == nil {
+		return true
+	}
+	return !s.skippedResourceTypes[resourceTypeID]
+}
+
+// syncsEntitlement reports whether the given entitlement of a resource type, and its grants, are synced.
+func (s *syncSelection) syncsEntitlement(resourceTypeID string, name string) bool {
+	if s == nil {
+		return true
+	}
+	return s.syncsResourceType(resourceTypeID) && !s.skippedEntitlements[resourceTypeID+":"+name]
+}
+
+// filterEntitlements drops the entitlements that aren't synced.
+func (s *syncSelection) filterEntitlements(resourceTypeID string, entitlements []*v2.Entitlement) []*v2.Entitlement {
+	var filtered []*v2.Entitlement
+	for _, e := range entitlements {
+		if s.syncsEntitlement(resourceTypeID, e.Slug) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// effective returns the synced resource type IDs and entitlements, the latter formatted as
+// "<resource type ID>:<entitlement name>".
+func (s *syncSelection) effective() ([]string, []string) {
+	var resourceTypeIDs, entitlements []string
+	for _, resourceType := range []*v2.ResourceType{userResourceType, teamResourceType, secretResourceType, scheduleResourceType} {
+		if !s.syncsResourceType(resourceType.Id) {
+			continue
+		}
+		resourceTypeIDs = append(resourceTypeIDs, resourceType.Id)
+		for _, name := range entitlementsByResourceType[resourceType.Id] {
+			if s.syncsEntitlement(resourceType.Id, name) {
+				entitlements = append(entitlements, resourceType.Id+":"+name)
+			}
+		}
+	}
+	return resourceTypeIDs, entitlements
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
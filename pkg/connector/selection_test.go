@@ -0,0 +1,259 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newSyncSelection(t *testing.T) {
+	tests := []struct {
+		name                string
+		skipResourceTypes   []string
+		skipEntitlements    []string
+		wantErr             string
+		wantResourceTypeIDs []string
+		wantEntitlements    []string
+	}{
+		{
+			name:                "everything by default",
+			wantResourceTypeIDs: []string{"user", "team", "secret", "schedule"},
+			wantEntitlements: []string{
+				"team:admin", "team:member", "schedule:owner", "schedule:member", "schedule:on-call",
+			},
+		},
+		{
+			name:                "skipped resource types and entitlements",
+			skipResourceTypes:   []string{"secret", " team"},
+			skipEntitlements:    []string{"schedule:on-call"},
+			wantResourceTypeIDs: []string{"user", "schedule"},
+			wantEntitlements:    []string{"schedule:owner", "schedule:member"},
+		},
+		{
+			name:              "users can't be skipped",
+			skipResourceTypes: []string{"user"},
+			wantErr:           "the user resource type can't be skipped",
+		},
+		{
+			name:              "unknown resource type",
+			skipResourceTypes: []string{"incident"},
+			wantErr:           `unknown resource type "incident" to skip`,
+		},
+		{
+			name:             "unknown entitlement",
+			skipEntitlements: []string{"schedule:admin"},
+			wantErr:          `unknown entitlement "schedule:admin" to skip`,
+		},
+		{
+			name:             "entitlement without resource type",
+			skipEntitlements: []string{"on-call"},
+			wantErr:          `unknown entitlement "on-call" to skip`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selection, err := newSyncSelection(tc.skipResourceTypes, tc.skipEntitlements)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			resourceTypeIDs, entitlements := selection.effective()
+			require.Equal(t, tc.wantResourceTypeIDs, resourceTypeIDs)
+			require.Equal(t, tc.wantEntitlements, entitlements)
+		})
+	}
+}
+
+func TestConnector_ResourceSyncersSkipsResourceTypes(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/users": `{"data": [], "links": {"next": null}}`,
+		"/v1/teams": `{"data": [], "links": {"next": null}}`,
+	})
+
+	ctx := context.Background()
+	c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL), WithSkippedResourceTypes("secret", "schedule"))
+	require.NoError(t, err)
+
+	var resourceTypeIDs []string
+	for _, syncer := range c.ResourceSyncers(ctx) {
+		resourceTypeIDs = append(resourceTypeIDs, syncer.ResourceType(ctx).Id)
+	}
+	require.Equal(t, []string{"user", "team"}, resourceTypeIDs)
+}
+
+func Test_scheduleBuilder_SkippedEntitlements(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/schedules/test-schedule-guid": scheduleGetResult,
+		"/v1/shifts":                       scheduleShiftsResult,
+		"/v1/schedules/test-schedule-guid/schedule_rotations": scheduleRotationsWithUsersResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection([]string{"team"}, []string{"schedule:on-call", "schedule:member"})
+	require.NoError(t, err)
+	builder := newScheduleBuilder(rootlyClient, newIncrementalSync(false, 0), client.DefaultOnCallWindow, selection)
+	resource := newTestScheduleResource(t)
+
+	entitlements, _, _, err := builder.Entitlements(ctx, resource, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, entitlements, 1)
+	require.Equal(t, scheduleOwnerEntitlement, entitlements[0].Slug)
+
+	// the owner team isn't granted since teams aren't synced
+	grants := listAllGrants(ctx, t, builder, resource)
+	require.Equal(t, []string{"schedule:test-schedule-guid:owner:user:96913"}, grantIDs(grants))
+	require.Equal(t, 0, fake.requestCount("/v1/shifts"))
+	require.Equal(t, 0, fake.requestCount("/v1/schedules/test-schedule-guid/schedule_rotations"))
+}
+
+func Test_scheduleBuilder_OwnerTeamExpandsSyncedTeamEntitlements(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/schedules/test-schedule-guid": scheduleGetResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection(nil, []string{"team:admin", "schedule:on-call", "schedule:member"})
+	require.NoError(t, err)
+	builder := newScheduleBuilder(rootlyClient, newIncrementalSync(false, 0), client.DefaultOnCallWindow, selection)
+
+	grants := listAllGrants(ctx, t, builder, newTestScheduleResource(t))
+	require.Equal(t, []string{
+		"schedule:test-schedule-guid:owner:user:96913",
+		"schedule:test-schedule-guid:owner:team:sre-team-guid",
+	}, grantIDs(grants))
+
+	grantAnnos := annotations.Annotations(grants[1].Annotations)
+	expandable := &v2.GrantExpandable{}
+	ok, err := grantAnnos.Pick(expandable)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"team:sre-team-guid:member"}, expandable.EntitlementIds)
+}
+
+func Test_syncSelection_nameFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		include        string
+		exclude        string
+		wantSynced     []string
+		wantListParams map[string]string
+	}{
+		{
+			name:       "no patterns",
+			wantSynced: []string{"prod-api", "prod-api-test", "PROD-web", "staging-api"},
+		},
+		{
+			name:           "include prefix",
+			include:        "^prod-",
+			wantSynced:     []string{"prod-api", "prod-api-test"},
+			wantListParams: map[string]string{"filter[search]": "prod-"},
+		},
+		{
+			name:           "include prefix and exclude",
+			include:        "^prod-",
+			exclude:        "-test$",
+			wantSynced:     []string{"prod-api"},
+			wantListParams: map[string]string{"filter[search]": "prod-"},
+		},
+		{
+			name:           "include exact name",
+			include:        "^prod-api$",
+			wantSynced:     []string{"prod-api"},
+			wantListParams: map[string]string{"filter[name]": "prod-api"},
+		},
+		{
+			name:       "include without literal prefix",
+			include:    "(?i)API$",
+			wantSynced: []string{"prod-api", "staging-api"},
+		},
+		{
+			// the search is narrowed to a literal prefix matched case-sensitively, which a case-insensitive
+			// pattern doesn't have
+			name:       "include case-insensitive prefix",
+			include:    "(?i)^prod-",
+			wantSynced: []string{"prod-api", "prod-api-test", "PROD-web"},
+		},
+		{
+			// the alternatives don't share a literal prefix, so every resource is listed
+			name:       "include alternation",
+			include:    "^prod-api$|^staging-",
+			wantSynced: []string{"prod-api", "staging-api"},
+		},
+		{
+			name:       "exclude only",
+			exclude:    "^staging-",
+			wantSynced: []string{"prod-api", "prod-api-test", "PROD-web"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selection, err := newSyncSelection(nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, selection.setNameFilter("team", tc.include, tc.exclude))
+
+			var synced []string
+			for _, name := range []string{"prod-api", "prod-api-test", "PROD-web", "staging-api"} {
+				if selection.syncsResourceName("team", name) {
+					synced = append(synced, name)
+				}
+				// other resource types aren't filtered
+				require.True(t, selection.syncsResourceName("schedule", name))
+			}
+			require.Equal(t, tc.wantSynced, synced)
+
+			listParams := make(map[string]string)
+			for _, opt := range selection.nameListOptions("team") {
+				opt(listParams)
+			}
+			if tc.wantListParams == nil {
+				require.Empty(t, listParams)
+			} else {
+				require.Equal(t, tc.wantListParams, listParams)
+			}
+		})
+	}
+
+	selection, err := newSyncSelection(nil, nil)
+	require.NoError(t, err)
+	require.ErrorContains(t, selection.setNameFilter("team", "prod-(", ""), "invalid team include pattern")
+}
+
+func Test_teamBuilder_ListFiltersByName(t *testing.T) {
+	var searches []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		searches = append(searches, request.URL.Query().Get("filter[search]"))
+		writer.Header().Set(uhttp.ContentType, "application/json")
+		_, _ = writer.Write([]byte(teamsListWithAndWithoutMemberships))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, server.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, selection.setNameFilter("team", "^SR", ""))
+	builder := newTeamBuilder(rootlyClient, selection)
+
+	teams, _, _, err := builder.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	require.Equal(t, "sre-team-guid", teams[0].Id.Resource)
+	require.Equal(t, []string{"SR"}, searches)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 
 	"github.com/conductorone/baton-rootly/pkg/connector/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -24,6 +25,71 @@ const (
 type teamBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.Client
+	memberships  *teamMembershipCache
+	selection    *syncSelection
+}
+
+// teamMembership holds the member and admin user IDs of a team, or whether the team was found deleted.
+type teamMembership struct {
+	memberIDs []int
+	adminIDs  []int
+	deleted   bool
+}
+
+// teamMembershipCache holds the team memberships learned while listing teams during a sync,
+// so that Grants doesn't need to fetch every team a second time.
+type teamMembershipCache struct {
+	mu          sync.Mutex
+	memberships map[string]teamMembership
+}
+
+func newTeamMembershipCache() *teamMembershipCache {
+	return &teamMembershipCache{
+		memberships: make(map[string]teamMembership),
+	}
+}
+
+// reset drops all cached memberships, e.g. when a new sync starts listing teams.
+func (c *teamMembershipCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memberships = make(map[string]teamMembership)
+}
+
+// set caches the membership of a team, unless the list response omitted the member or admin arrays.
+// It reports whether the membership was cached.
+func (c *teamMembershipCache) set(team client.Team) bool {
+	if team.Attributes.UserIDs == nil || team.Attributes.AdminIDs == nil {
+		return false
+	}
+	c.setMembers(team.ID, team.Attributes.UserIDs, team.Attributes.AdminIDs)
+	return true
+}
+
+// setMembers caches the member and admin user IDs of a team.
+func (c *teamMembershipCache) setMembers(teamID string, memberIDs []int, adminIDs []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memberships[teamID] = teamMembership{
+		memberIDs: memberIDs,
+		adminIDs:  adminIDs,
+	}
+}
+
+// setDeleted caches that a team was deleted since it was listed.
+func (c *teamMembershipCache) setDeleted(teamID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memberships[teamID] = teamMembership{deleted: true}
+}
+
+// pop returns and removes the cached membership of a team, since grants are only fetched once per sync.
+func (c *teamMembershipCache) pop(teamID string) (teamMembership, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	membership, ok := c.memberships[teamID]
+	delete(c.memberships, teamID)
+	return membership, ok
 }
 
 func (o *teamBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -52,22 +118,32 @@ func (o *teamBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		})
 	}
 
-	// fetch teams from the Rootly API with pagination
-	teams, token, err := o.client.GetTeams(ctx, bag.PageToken())
+	// a new sync lists teams from the first page, so forget memberships cached by any previous sync
+	if pToken.Token == "" {
+		o.memberships.reset()
+	}
+
+	// fetch teams from the Rootly API with pagination, narrowed server-side by the include pattern when possible
+	teams, token, err := o.client.GetTeams(ctx, bag.PageToken(), o.selection.nameListOptions(o.resourceType.Id)...)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	// create team resources using the SDK
 	var resources []*v2.Resource
+	var uncachedTeamIDs []string
 	for _, team := range teams {
-		teamResource, err := sdkResource.NewGroupResource(
-			team.Attributes.Name,
-			o.resourceType,
-			team.ID,
-			getTeamTraitOptions(team),
-			sdkResource.WithParentResourceID(parentResourceID),
-		)
+		// skip teams filtered out by name, so that neither they nor their grants are synced
+		if !o.selection.syncsResourceName(o.resourceType.Id, team.Attributes.Name) {
+			continue
+		}
+
+		// the list response usually includes memberships already, cache them for Grants
+		if !o.memberships.set(team) {
+			uncachedTeamIDs = append(uncachedTeamIDs, team.ID)
+		}
+
+		teamResource, err := newTeamResource(team, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -75,13 +151,64 @@ func (o *teamBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		resources = append(resources, teamResource)
 	}
 
+	// otherwise fetch the memberships of the page's teams concurrently, rather than one team per Grants call
+	if o.syncsGrants() && len(uncachedTeamIDs) > 0 {
+		memberships, err := o.client.GetTeamsMemberAndAdminIDs(ctx, uncachedTeamIDs)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		for _, membership := range memberships {
+			if membership.Deleted {
+				// the team was deleted since the page was listed, Grants skips it
+				o.memberships.setDeleted(membership.TeamID)
+				continue
+			}
+			o.memberships.setMembers(membership.TeamID, membership.UserIDs, membership.AdminIDs)
+		}
+	}
+
 	// set the next page token
 	nextPage, err := bag.NextToken(token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	return resources, nextPage, nil, nil
+	return resources, nextPage, withRateLimit(nil, o.client), nil
+}
+
+// Get returns a single team, e.g. for a targeted sync after its memberships changed, or nil if it no longer exists or
+// is filtered out by name.
+func (o *teamBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, parentResourceID *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	team, err := o.client.GetTeam(ctx, resourceID.Resource)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !o.selection.syncsResourceName(o.resourceType.Id, team.Attributes.Name) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+
+	// the team response includes memberships, cache them for Grants
+	o.memberships.set(*team)
+
+	teamResource, err := newTeamResource(*team, parentResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return teamResource, withRateLimit(nil, o.client), nil
+}
+
+// newTeamResource creates a team resource using the SDK.
+func newTeamResource(team client.Team, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return sdkResource.NewGroupResource(
+		team.Attributes.Name,
+		teamResourceType,
+		team.ID,
+		getTeamTraitOptions(team),
+		sdkResource.WithParentResourceID(parentResourceID),
+	)
 }
 
 // getTeamTraitOptions returns a list of GroupTraitOption's based on the available fields for a Rootly team.
@@ -99,9 +226,13 @@ func getTeamTraitOptions(team client.Team) []sdkResource.GroupTraitOption {
 		profile["description"] = team.Attributes.Description
 	}
 
-	return []sdkResource.GroupTraitOption{
+	traitOpts := []sdkResource.GroupTraitOption{
 		sdkResource.WithGroupProfile(profile),
 	}
+	if team.Attributes.IconURL != "" {
+		traitOpts = append(traitOpts, sdkResource.WithGroupIcon(assetRef(teamResourceType.Id, team.ID)))
+	}
+	return traitOpts
 }
 
 // Entitlements for each team include administration and membership.
@@ -117,7 +248,7 @@ func (o *teamBuilder) Entitlements(
 		zap.String("resource.Id.Resource", resource.Id.Resource),
 	)
 
-	return []*v2.Entitlement{
+	return o.selection.filterEntitlements(o.resourceType.Id, []*v2.Entitlement{
 		entitlement.NewAssignmentEntitlement(
 			resource,
 			teamAdminEntitlement,
@@ -132,7 +263,7 @@ func (o *teamBuilder) Entitlements(
 			entitlement.WithDisplayName(fmt.Sprintf("%s Team Member", resource.DisplayName)),
 			entitlement.WithDescription(fmt.Sprintf("Is member of the %s team in Rootly", resource.DisplayName)),
 		),
-	}, "", nil, nil
+	}), "", nil, nil
 }
 
 // Grants for each team are the current administration and memberships.
@@ -154,10 +285,35 @@ func (o *teamBuilder) Grants(
 		})
 	}
 
-	// fetch team member and admin userIDs from the Rootly API
-	memberIDs, adminIDs, err := o.client.GetTeamMemberAndAdminIDs(ctx, resource.Id.Resource)
-	if err != nil {
-		return nil, "", nil, err
+	if !o.syncsGrants() {
+		return nil, "", nil, nil
+	}
+	syncsMembers := o.selection.syncsEntitlement(o.resourceType.Id, teamMemberEntitlement)
+	syncsAdmins := o.selection.syncsEntitlement(o.resourceType.Id, teamAdminEntitlement)
+
+	// use the team member and admin userIDs cached while listing teams,
+	// falling back to fetching them from the Rootly API
+	var memberIDs, adminIDs []int
+	if membership, ok := o.memberships.pop(resource.Id.Resource); ok {
+		if membership.deleted {
+			return deletedResourceGrants(ctx, o.client, resource, nil)
+		}
+		memberIDs, adminIDs = membership.memberIDs, membership.adminIDs
+	} else {
+		memberIDs, adminIDs, err = o.client.GetTeamMemberAndAdminIDs(ctx, resource.Id.Resource)
+		if client.IsNotFound(err) {
+			return deletedResourceGrants(ctx, o.client, resource, err)
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	if !syncsMembers {
+		memberIDs = nil
+	}
+	if !syncsAdmins {
+		adminIDs = nil
 	}
 
 	var grants []*v2.Grant
@@ -184,12 +340,20 @@ func (o *teamBuilder) Grants(
 		))
 	}
 
-	return grants, "", nil, nil
+	return grants, "", withRateLimit(nil, o.client), nil
+}
+
+// syncsGrants reports whether any team entitlement is synced, and so whether team grants are needed.
+func (o *teamBuilder) syncsGrants() bool {
+	return o.selection.syncsEntitlement(o.resourceType.Id, teamMemberEntitlement) ||
+		o.selection.syncsEntitlement(o.resourceType.Id, teamAdminEntitlement)
 }
 
-func newTeamBuilder(client *client.Client) *teamBuilder {
+func newTeamBuilder(client *client.Client, selection *syncSelection) *teamBuilder {
 	return &teamBuilder{
 		client:       client,
 		resourceType: teamResourceType,
+		memberships:  newTeamMembershipCache(),
+		selection:    selection,
 	}
 }
@@ -0,0 +1,202 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	rootlyv1 "github.com/conductorone/baton-rootly/pb/rootly/v1"
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	teamsListWithAndWithoutMemberships = `{
+    "data": [
+        {
+            "id": "sre-team-guid",
+            "type": "groups",
+            "attributes": {
+                "name": "SRE",
+                "user_ids": [96913, 97487],
+                "admin_ids": [96913],
+                "created_at": "2025-03-28T07:05:55.007-07:00",
+                "updated_at": "2025-04-07T07:54:11.604-07:00"
+            }
+        },
+        {
+            "id": "security-team-guid",
+            "type": "groups",
+            "attributes": {
+                "name": "Security",
+                "created_at": "2025-03-28T07:05:55.007-07:00",
+                "updated_at": "2025-04-07T07:54:11.604-07:00"
+            }
+        }
+    ],
+    "links": {
+        "next": null
+    }
+}`
+	sreTeamGetResult = `{
+    "data": {
+        "id": "sre-team-guid",
+        "type": "groups",
+        "attributes": {
+            "name": "SRE",
+            "user_ids": [96913, 97487],
+            "admin_ids": [96913],
+            "created_at": "2025-03-28T07:05:55.007-07:00",
+            "updated_at": "2025-04-07T07:54:11.604-07:00"
+        }
+    }
+}`
+	securityTeamGetResult = `{
+    "data": {
+        "id": "security-team-guid",
+        "type": "groups",
+        "attributes": {
+            "name": "Security",
+            "user_ids": [97487],
+            "admin_ids": [],
+            "created_at": "2025-03-28T07:05:55.007-07:00",
+            "updated_at": "2025-04-07T07:54:11.604-07:00"
+        }
+    }
+}`
+)
+
+func Test_teamBuilder_GrantsUseListedMemberships(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/teams":                    teamsListWithAndWithoutMemberships,
+		"/v1/teams/sre-team-guid":      sreTeamGetResult,
+		"/v1/teams/security-team-guid": securityTeamGetResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	builder := newTeamBuilder(rootlyClient, nil)
+
+	teams, nextPage, _, err := builder.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, nextPage)
+	require.Len(t, teams, 2)
+	// the memberships omitted by the list response are fetched along with the page
+	require.Equal(t, 1, fake.requestCount("/v1/teams/security-team-guid"))
+
+	grantsByTeam := make(map[string][]string)
+	for _, team := range teams {
+		grants, nextPage, _, err := builder.Grants(ctx, team, &pagination.Token{})
+		require.NoError(t, err)
+		require.Empty(t, nextPage)
+		for _, g := range grants {
+			grantsByTeam[team.Id.Resource] = append(grantsByTeam[team.Id.Resource], g.Id)
+		}
+	}
+
+	require.ElementsMatch(t, []string{
+		"team:sre-team-guid:member:user:96913",
+		"team:sre-team-guid:member:user:97487",
+		"team:sre-team-guid:admin:user:96913",
+	}, grantsByTeam["sre-team-guid"])
+	require.ElementsMatch(t, []string{
+		"team:security-team-guid:member:user:97487",
+	}, grantsByTeam["security-team-guid"])
+
+	require.Equal(t, 1, fake.requestCount("/v1/teams"))
+	// the memberships of the SRE team came with the list response
+	require.Equal(t, 0, fake.requestCount("/v1/teams/sre-team-guid"))
+	// the list response omitted the memberships of the Security team
+	require.Equal(t, 1, fake.requestCount("/v1/teams/security-team-guid"))
+
+	// a cache miss, e.g. for a targeted sync, falls back to the detail request
+	_, _, _, err = builder.Grants(ctx, teams[0], &pagination.Token{})
+	require.NoError(t, err)
+	require.Equal(t, 1, fake.requestCount("/v1/teams/sre-team-guid"))
+}
+
+func Test_teamBuilder_GrantsSkipDeletedTeam(t *testing.T) {
+	// the Security team was deleted between List and Grants, so its detail endpoint responds 404
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/teams":               teamsListWithAndWithoutMemberships,
+		"/v1/teams/sre-team-guid": sreTeamGetResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	builder := newTeamBuilder(rootlyClient, nil)
+
+	teams, _, _, err := builder.List(ctx, nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, teams, 2)
+
+	grants, nextPage, annos, err := builder.Grants(ctx, teams[1], &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, nextPage)
+	require.Empty(t, grants)
+	require.Equal(t, []string{"team security-team-guid"}, warnings(t, annos))
+	require.Equal(t, 1, fake.requestCount("/v1/teams/security-team-guid"))
+
+	// other errors still fail the sync
+	fake.Close()
+	_, _, _, err = builder.Grants(ctx, teams[1], &pagination.Token{})
+	require.Error(t, err)
+}
+
+// warnings returns the resource each warning annotation is about, as "<resource type ID> <resource ID>".
+func warnings(t *testing.T, annos annotations.Annotations) []string {
+	var warnings []string
+	for _, a := range annos {
+		warning := &rootlyv1.Warning{}
+		if !a.MessageIs(warning) {
+			continue
+		}
+		require.NoError(t, a.UnmarshalTo(warning))
+		require.NotEmpty(t, warning.GetMessage())
+		warnings = append(warnings, warning.GetResourceTypeId()+" "+warning.GetResourceId())
+	}
+	return warnings
+}
+
+func Test_teamBuilder_Get(t *testing.T) {
+	fake := newFakeRootly(t)
+	fake.respond(map[string]string{
+		"/v1/teams/sre-team-guid":      sreTeamGetResult,
+		"/v1/teams/security-team-guid": securityTeamGetResult,
+	})
+
+	ctx := context.Background()
+	rootlyClient, err := client.NewClient(ctx, fake.URL, "test-api-key", client.ResourcesPageSize)
+	require.NoError(t, err)
+	selection, err := newSyncSelection(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, selection.setNameFilter(teamResourceType.Id, "", "^Security$"))
+	builder := newTeamBuilder(rootlyClient, selection)
+
+	team, _, err := builder.Get(ctx, &v2.ResourceId{ResourceType: "team", Resource: "sre-team-guid"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "SRE", team.DisplayName)
+	require.Equal(t, "sre-team-guid", team.Id.Resource)
+
+	// the memberships of the team are cached for the targeted Grants call
+	grants, _, _, err := builder.Grants(ctx, team, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, grants, 3)
+	require.Equal(t, 1, fake.requestCount("/v1/teams/sre-team-guid"))
+
+	// teams filtered out by name aren't returned
+	team, _, err = builder.Get(ctx, &v2.ResourceId{ResourceType: "team", Resource: "security-team-guid"}, nil)
+	require.NoError(t, err)
+	require.Nil(t, team)
+
+	// nor are deleted teams
+	team, _, err = builder.Get(ctx, &v2.ResourceId{ResourceType: "team", Resource: "deleted-team-guid"}, nil)
+	require.NoError(t, err)
+	require.Nil(t, team)
+}
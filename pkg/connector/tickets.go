@@ -0,0 +1,505 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rootlyv1 "github.com/conductorone/baton-rootly/pb/rootly/v1"
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkTicket "github.com/conductorone/baton-sdk/pkg/types/ticket"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	incidentTicketType   = "incident"
+	actionItemTicketType = "action_item"
+
+	severityTicketField   = "severity"
+	incidentIDTicketField = "incident_id"
+	// formFieldTicketFieldPrefix prefixes the ID of a Rootly custom form field to make the ID of its ticket field.
+	formFieldTicketFieldPrefix = "form_field:"
+
+	// defaultTicketSchemaID is the ID of the only ticket schema when Rootly has no incident types.
+	defaultTicketSchemaID = "incident"
+)
+
+var (
+	ticketTypes = []*v2.TicketType{
+		{Id: incidentTicketType, DisplayName: "Incident"},
+		{Id: actionItemTicketType, DisplayName: "Action item"},
+	}
+
+	// incidentTicketStatuses are the statuses of Rootly incidents, in the order of their lifecycle.
+	incidentTicketStatuses = []*v2.TicketStatus{
+		{Id: "in_triage", DisplayName: "In triage"},
+		{Id: "started", DisplayName: "Started"},
+		{Id: "mitigated", DisplayName: "Mitigated"},
+		{Id: "resolved", DisplayName: "Resolved"},
+		{Id: "closed", DisplayName: "Closed"},
+		{Id: "cancelled", DisplayName: "Cancelled"},
+	}
+
+	// actionItemTicketStatuses are the statuses of Rootly action items, in the order of their lifecycle.
+	actionItemTicketStatuses = []*v2.TicketStatus{
+		{Id: "open", DisplayName: "Open"},
+		{Id: "in_progress", DisplayName: "In progress"},
+		{Id: "done", DisplayName: "Done"},
+		{Id: "cancelled", DisplayName: "Cancelled"},
+	}
+)
+
+// ticketFieldsCache holds the custom fields of the ticket schemas computed for the first page of ticket schemas, so
+// that the following pages don't fetch and compute them again.
+type ticketFieldsCache struct {
+	mu     sync.Mutex
+	fields map[string]*v2.TicketCustomField
+}
+
+// get returns the cached custom fields, computing them with compute for the first page, which starts a new listing of
+// the ticket schemas, or when none are cached yet.
+func (c *ticketFieldsCache) get(
+	ctx context.Context,
+	firstPage bool,
+	compute func(ctx context.Context) (map[string]*v2.TicketCustomField, error),
+) (map[string]*v2.TicketCustomField, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if firstPage || c.fields == nil {
+		fields, err := compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.fields = fields
+	}
+	return c.fields, nil
+}
+
+// GetTicketSchema returns the ticket schema of an incident type.
+func (d *Connector) GetTicketSchema(ctx context.Context, schemaID string) (*v2.TicketSchema, annotations.Annotations, error) {
+	customFields, err := d.ticketCustomFields(ctx)
+	if err != nil {
+		return nil, withRateLimit(nil, d.client), err
+	}
+	if schemaID == defaultTicketSchemaID {
+		return newTicketSchema(defaultTicketSchemaID, "Incident", customFields), withRateLimit(nil, d.client), nil
+	}
+
+	incidentType, err := d.client.GetIncidentType(ctx, schemaID)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, d.client), status.Errorf(codes.NotFound, "unknown ticket schema %q", schemaID)
+	}
+	if err != nil {
+		return nil, withRateLimit(nil, d.client), err
+	}
+	return newTicketSchema(incidentType.ID, incidentType.Attributes.Name, customFields), withRateLimit(nil, d.client), nil
+}
+
+// ListTicketSchemas returns a ticket schema for each incident type, or a single schema for incidents without type
+// when Rootly has no incident types.
+func (d *Connector) ListTicketSchemas(
+	ctx context.Context,
+	pToken *pagination.Token,
+) ([]*v2.TicketSchema, string, annotations.Annotations, error) {
+	incidentTypes, nextPage, err := d.client.ListIncidentTypes(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", withRateLimit(nil, d.client), err
+	}
+	customFields, err := d.ticketFields.get(ctx, pToken.Token == "", d.ticketCustomFields)
+	if err != nil {
+		return nil, "", withRateLimit(nil, d.client), err
+	}
+
+	if pToken.Token == "" && nextPage == "" && len(incidentTypes) == 0 {
+		return []*v2.TicketSchema{
+			newTicketSchema(defaultTicketSchemaID, "Incident", customFields),
+		}, "", withRateLimit(nil, d.client), nil
+	}
+	schemas := make([]*v2.TicketSchema, 0, len(incidentTypes))
+	for _, incidentType := range incidentTypes {
+		schemas = append(schemas, newTicketSchema(incidentType.ID, incidentType.Attributes.Name, customFields))
+	}
+	return schemas, nextPage, withRateLimit(nil, d.client), nil
+}
+
+// CreateTicket creates a Rootly incident of the incident type of the schema, or an action item of an existing incident
+// for tickets of the action item type.
+func (d *Connector) CreateTicket(
+	ctx context.Context,
+	ticket *v2.Ticket,
+	schema *v2.TicketSchema,
+) (*v2.Ticket, annotations.Annotations, error) {
+	valid, err := sdkTicket.ValidateTicket(ctx, schema, ticket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "the ticket doesn't match ticket schema %s", schema.GetId())
+	}
+
+	switch ticket.GetType().GetId() {
+	case "", incidentTicketType:
+		return d.createIncidentTicket(ctx, ticket, schema)
+	case actionItemTicketType:
+		return d.createActionItemTicket(ctx, ticket)
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "unknown ticket type %q", ticket.GetType().GetId())
+	}
+}
+
+// GetTicket returns a ticket with the current status of its Rootly incident or action item.
+func (d *Connector) GetTicket(ctx context.Context, ticketID string) (*v2.Ticket, annotations.Annotations, error) {
+	ticketType, id, ok := strings.Cut(ticketID, ":")
+	if !ok || id == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid ticket ID %q", ticketID)
+	}
+
+	switch ticketType {
+	case incidentTicketType:
+		incident, err := d.client.GetIncident(ctx, id)
+		if err != nil {
+			return nil, withRateLimit(nil, d.client), err
+		}
+		return incidentTicket(incident), withRateLimit(nil, d.client), nil
+	case actionItemTicketType:
+		actionItem, err := d.client.GetActionItem(ctx, id)
+		if err != nil {
+			return nil, withRateLimit(nil, d.client), err
+		}
+		return actionItemTicket(actionItem), withRateLimit(nil, d.client), nil
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid ticket ID %q", ticketID)
+	}
+}
+
+// BulkCreateTickets creates each ticket in turn, reporting the error of each ticket that couldn't be created.
+func (d *Connector) BulkCreateTickets(
+	ctx context.Context,
+	request *v2.TicketsServiceBulkCreateTicketsRequest,
+) (*v2.TicketsServiceBulkCreateTicketsResponse, error) {
+	responses := make([]*v2.TicketsServiceCreateTicketResponse, 0, len(request.GetTicketRequests()))
+	for _, ticketRequest := range request.GetTicketRequests() {
+		req := ticketRequest.GetRequest()
+		ticket, annos, err := d.CreateTicket(ctx, &v2.Ticket{
+			DisplayName:  req.GetDisplayName(),
+			Description:  req.GetDescription(),
+			Status:       req.GetStatus(),
+			Type:         req.GetType(),
+			Labels:       req.GetLabels(),
+			CustomFields: req.GetCustomFields(),
+			RequestedFor: req.GetRequestedFor(),
+		}, ticketRequest.GetSchema())
+		response := &v2.TicketsServiceCreateTicketResponse{
+			Ticket:      ticket,
+			Annotations: annos,
+		}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		responses = append(responses, response)
+	}
+	return &v2.TicketsServiceBulkCreateTicketsResponse{Tickets: responses}, nil
+}
+
+// BulkGetTickets gets each ticket in turn, reporting the error of each ticket that couldn't be fetched.
+func (d *Connector) BulkGetTickets(
+	ctx context.Context,
+	request *v2.TicketsServiceBulkGetTicketsRequest,
+) (*v2.TicketsServiceBulkGetTicketsResponse, error) {
+	responses := make([]*v2.TicketsServiceGetTicketResponse, 0, len(request.GetTicketRequests()))
+	for _, ticketRequest := range request.GetTicketRequests() {
+		ticket, annos, err := d.GetTicket(ctx, ticketRequest.GetId())
+		response := &v2.TicketsServiceGetTicketResponse{
+			Ticket:      ticket,
+			Annotations: annos,
+		}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		responses = append(responses, response)
+	}
+	return &v2.TicketsServiceBulkGetTicketsResponse{Tickets: responses}, nil
+}
+
+// createIncidentTicket creates an incident for a ticket, then sets the custom form fields of the ticket on it.
+func (d *Connector) createIncidentTicket(
+	ctx context.Context,
+	ticket *v2.Ticket,
+	schema *v2.TicketSchema,
+) (*v2.Ticket, annotations.Annotations, error) {
+	attributes := client.IncidentRequestAttributes{
+		Title:   ticket.GetDisplayName(),
+		Summary: ticket.GetDescription(),
+	}
+	if schema.GetId() != defaultTicketSchemaID {
+		attributes.IncidentTypeIDs = []string{schema.GetId()}
+	}
+	if ticket.GetStatus() != nil {
+		if !hasTicketStatus(incidentTicketStatuses, ticket.GetStatus().GetId()) {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid incident status %q", ticket.GetStatus().GetId())
+		}
+		attributes.Status = ticket.GetStatus().GetId()
+	}
+	severity, err := sdkTicket.GetPickObjectValue(ticket.GetCustomFields()[severityTicketField])
+	if err == nil {
+		attributes.SeverityID = severity.GetId()
+	}
+
+	incident, err := d.client.CreateIncident(ctx, attributes)
+	if err != nil {
+		return nil, withRateLimit(nil, d.client), err
+	}
+	ctxzap.Extract(ctx).Info(
+		"Created incident for ticket",
+		zap.String("incident_id", incident.ID),
+		zap.String("ticket_schema_id", schema.GetId()),
+	)
+
+	// the incident exists from now on, so failing to set a form field is only a warning, lest the ticket be created
+	// again on retry
+	var annos annotations.Annotations
+	for id, field := range ticket.GetCustomFields() {
+		formFieldID, ok := strings.CutPrefix(id, formFieldTicketFieldPrefix)
+		if !ok {
+			continue
+		}
+		selection, ok, err := formFieldSelection(formFieldID, field)
+		if err == nil && ok {
+			err = d.client.CreateFormFieldSelection(ctx, incident.ID, selection)
+		}
+		if err != nil {
+			annos = withWarning(ctx, annos, &rootlyv1.Warning{
+				Message: fmt.Sprintf("failed to set form field %s of incident %s created for ticket: %s", formFieldID, incident.ID, err),
+			})
+		}
+	}
+	return incidentTicket(incident), withRateLimit(annos, d.client), nil
+}
+
+// createActionItemTicket creates an action item of the incident of a ticket.
+func (d *Connector) createActionItemTicket(ctx context.Context, ticket *v2.Ticket) (*v2.Ticket, annotations.Annotations, error) {
+	incidentID, err := sdkTicket.GetStringValue(ticket.GetCustomFields()[incidentIDTicketField])
+	if err != nil || incidentID == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "%s is required for action items", incidentIDTicketField)
+	}
+	attributes := client.ActionItemRequestAttributes{
+		Summary:     ticket.GetDisplayName(),
+		Description: ticket.GetDescription(),
+	}
+	if ticket.GetStatus() != nil {
+		if !hasTicketStatus(actionItemTicketStatuses, ticket.GetStatus().GetId()) {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid action item status %q", ticket.GetStatus().GetId())
+		}
+		attributes.Status = ticket.GetStatus().GetId()
+	}
+
+	actionItem, err := d.client.CreateActionItem(ctx, incidentID, attributes)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, d.client), status.Errorf(codes.InvalidArgument, "unknown incident %q", incidentID)
+	}
+	if err != nil {
+		return nil, withRateLimit(nil, d.client), err
+	}
+	ctxzap.Extract(ctx).Info(
+		"Created action item for ticket",
+		zap.String("incident_id", incidentID),
+		zap.String("action_item_id", actionItem.ID),
+	)
+	return actionItemTicket(actionItem), withRateLimit(nil, d.client), nil
+}
+
+// ticketCustomFields returns the custom fields shared by the ticket schemas: the severity and custom form fields of
+// incidents, and the incident of action items.
+func (d *Connector) ticketCustomFields(ctx context.Context) (map[string]*v2.TicketCustomField, error) {
+	logger := ctxzap.Extract(ctx)
+	severities, err := d.client.ListAllSeverities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	severityValues := make([]*v2.TicketCustomFieldObjectValue, 0, len(severities))
+	for _, severity := range severities {
+		severityValues = append(severityValues, &v2.TicketCustomFieldObjectValue{
+			Id:          severity.ID,
+			DisplayName: severity.Attributes.Name,
+		})
+	}
+	customFields := map[string]*v2.TicketCustomField{
+		severityTicketField:   sdkTicket.PickObjectValueFieldSchema(severityTicketField, "Severity", false, severityValues),
+		incidentIDTicketField: sdkTicket.StringFieldSchema(incidentIDTicketField, "Incident ID (action items only)", false),
+	}
+
+	formFields, err := d.client.ListAllFormFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, formField := range formFields {
+		if formField.Attributes.Kind != "custom" || !formField.Attributes.Enabled {
+			continue
+		}
+		id := formFieldTicketFieldPrefix + formField.ID
+		name := formField.Attributes.Name
+		switch formField.Attributes.InputKind {
+		case "text", "textarea", "rich_text":
+			customFields[id] = sdkTicket.StringFieldSchema(id, name, false)
+		case "number":
+			customFields[id] = sdkTicket.NumberFieldSchema(id, name, false)
+		case "date", "datetime":
+			customFields[id] = sdkTicket.TimestampFieldSchema(id, name, false)
+		case "select", "multi_select", "checkbox", "tags":
+			options, err := d.client.ListAllFormFieldOptions(ctx, formField.ID)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]*v2.TicketCustomFieldObjectValue, 0, len(options))
+			for _, option := range options {
+				values = append(values, &v2.TicketCustomFieldObjectValue{
+					Id:          option.ID,
+					DisplayName: option.Attributes.Value,
+				})
+			}
+			if formField.Attributes.InputKind == "select" {
+				customFields[id] = sdkTicket.PickObjectValueFieldSchema(id, name, false, values)
+			} else {
+				customFields[id] = sdkTicket.PickMultipleObjectValuesFieldSchema(id, name, false, values)
+			}
+		default:
+			logger.Debug(
+				"Skipping form field of an unsupported input kind",
+				zap.String("form_field.ID", formField.ID),
+				zap.String("input_kind", formField.Attributes.InputKind),
+			)
+		}
+	}
+	return customFields, nil
+}
+
+// newTicketSchema returns the ticket schema of an incident type.
+func newTicketSchema(id string, displayName string, customFields map[string]*v2.TicketCustomField) *v2.TicketSchema {
+	statuses := append([]*v2.TicketStatus{}, incidentTicketStatuses...)
+	for _, actionItemStatus := range actionItemTicketStatuses {
+		if !hasTicketStatus(statuses, actionItemStatus.Id) {
+			statuses = append(statuses, actionItemStatus)
+		}
+	}
+	return &v2.TicketSchema{
+		Id:           id,
+		DisplayName:  displayName,
+		Types:        ticketTypes,
+		Statuses:     statuses,
+		CustomFields: customFields,
+	}
+}
+
+// formFieldSelection returns the selection setting a custom form field to the value of its ticket field, and whether
+// the ticket field has a value.
+func formFieldSelection(formFieldID string, field *v2.TicketCustomField) (client.FormFieldSelectionRequestAttributes, bool, error) {
+	selection := client.FormFieldSelectionRequestAttributes{FormFieldID: formFieldID}
+	value, err := sdkTicket.GetCustomFieldValue(field)
+	if err != nil {
+		return selection, false, err
+	}
+	switch v := value.(type) {
+	case string:
+		selection.Value = v
+	case float32:
+		selection.Value = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case *timestamppb.Timestamp:
+		if v == nil {
+			return selection, false, nil
+		}
+		selection.Value = v.AsTime().UTC().Format(time.RFC3339)
+	case *v2.TicketCustomFieldObjectValue:
+		if v == nil {
+			return selection, false, nil
+		}
+		selection.SelectedOptionIDs = []string{v.GetId()}
+	case []*v2.TicketCustomFieldObjectValue:
+		for _, option := range v {
+			selection.SelectedOptionIDs = append(selection.SelectedOptionIDs, option.GetId())
+		}
+	case nil:
+		return selection, false, nil
+	default:
+		return selection, false, fmt.Errorf("unsupported value %v", value)
+	}
+	return selection, true, nil
+}
+
+// incidentTicket returns the ticket of an incident, completed once the incident is resolved, closed or cancelled.
+func incidentTicket(incident *client.Incident) *v2.Ticket {
+	attributes := incident.Attributes
+	ticket := &v2.Ticket{
+		Id:          fmt.Sprintf("%s:%s", incidentTicketType, incident.ID),
+		DisplayName: attributes.Title,
+		Description: attributes.Summary,
+		Status:      ticketStatus(incidentTicketStatuses, attributes.Status),
+		Type:        ticketTypes[0],
+		Url:         attributes.URL,
+		CreatedAt:   parseTimestamp(attributes.CreatedAt),
+		UpdatedAt:   parseTimestamp(attributes.UpdatedAt),
+	}
+	for _, completedAt := range []string{attributes.ResolvedAt, attributes.ClosedAt, attributes.CancelledAt} {
+		if ticket.CompletedAt = parseTimestamp(completedAt); ticket.CompletedAt != nil {
+			break
+		}
+	}
+	return ticket
+}
+
+// actionItemTicket returns the ticket of an action item.
+func actionItemTicket(actionItem *client.ActionItem) *v2.Ticket {
+	attributes := actionItem.Attributes
+	ticket := &v2.Ticket{
+		Id:          fmt.Sprintf("%s:%s", actionItemTicketType, actionItem.ID),
+		DisplayName: attributes.Summary,
+		Description: attributes.Description,
+		Status:      ticketStatus(actionItemTicketStatuses, attributes.Status),
+		Type:        ticketTypes[1],
+		Url:         attributes.URL,
+		CreatedAt:   parseTimestamp(attributes.CreatedAt),
+		UpdatedAt:   parseTimestamp(attributes.UpdatedAt),
+	}
+	if attributes.Status == "done" || attributes.Status == "cancelled" {
+		ticket.CompletedAt = ticket.UpdatedAt
+	}
+	return ticket
+}
+
+// ticketStatus returns the ticket status of a Rootly status, falling back to the Rootly status itself for the statuses
+// added after this connector.
+func ticketStatus(statuses []*v2.TicketStatus, id string) *v2.TicketStatus {
+	for _, ticketStatus := range statuses {
+		if ticketStatus.Id == id {
+			return ticketStatus
+		}
+	}
+	return &v2.TicketStatus{Id: id, DisplayName: id}
+}
+
+func hasTicketStatus(statuses []*v2.TicketStatus, id string) bool {
+	for _, ticketStatus := range statuses {
+		if ticketStatus.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimestamp parses a Rootly timestamp, returning nil when it's empty or invalid.
+func parseTimestamp(timestamp string) *timestamppb.Timestamp {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
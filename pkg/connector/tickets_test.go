@@ -0,0 +1,311 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	sdkTicket "github.com/conductorone/baton-sdk/pkg/types/ticket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ connectorbuilder.TicketManager = (*Connector)(nil)
+
+// ticketState holds the incident types served by serveTickets, and the status it serves for the created incident.
+type ticketState struct {
+	mu             sync.Mutex
+	incidentTypes  string
+	incidentStatus string
+}
+
+func (s *ticketState) setIncidentTypes(incidentTypes string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.incidentTypes = incidentTypes
+}
+
+func (s *ticketState) setIncidentStatus(incidentStatus string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.incidentStatus = incidentStatus
+}
+
+// serveTickets registers the endpoints serving the severities, incident types and form fields of the ticket schemas,
+// and creating incidents, form field selections and action items, on the fake Rootly API.
+func serveTickets(t *testing.T, fake *fakeRootly) *ticketState {
+	state := &ticketState{
+		incidentTypes: `[
+			{"id": "test-incident-type-guid", "type": "incident_types", "attributes": {"name": "Access request"}}
+		]`,
+		incidentStatus: "started",
+	}
+	incident := func() client.IncidentResponse {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return client.IncidentResponse{Data: client.Incident{
+			ID:   "test-incident-guid",
+			Type: "incidents",
+			Attributes: client.IncidentAttributes{
+				Title:      "Access to production",
+				Summary:    "Jane needs access to production",
+				Status:     state.incidentStatus,
+				URL:        "https://rootly.com/account/incidents/1",
+				ResolvedAt: map[bool]string{true: "2025-04-10T10:00:00Z"}[state.incidentStatus == "resolved"],
+				CreatedAt:  "2025-04-10T09:00:00Z",
+				UpdatedAt:  "2025-04-10T09:00:00Z",
+			},
+		}}
+	}
+	fake.respond(map[string]string{
+		"GET /v1/severities": `{
+			"data": [
+				{"id": "test-sev0-guid", "type": "severities", "attributes": {"name": "SEV0", "severity": "critical"}},
+				{"id": "test-sev1-guid", "type": "severities", "attributes": {"name": "SEV1", "severity": "high"}}
+			],
+			"links": {"next": null}
+		}`,
+		"GET /v1/incident_types/test-incident-type-guid": `{"data": {"id": "test-incident-type-guid", "type": "incident_types", "attributes": {"name": "Access request"}}}`,
+		"GET /v1/form_fields": `{
+			"data": [
+				{"id": "test-title-field-guid", "type": "form_fields", "attributes": {"kind": "title", "input_kind": "text", "name": "Title", "enabled": true}},
+				{"id": "test-system-field-guid", "type": "form_fields", "attributes": {"kind": "custom", "input_kind": "select", "name": "System", "enabled": true}},
+				{"id": "test-reason-field-guid", "type": "form_fields", "attributes": {"kind": "custom", "input_kind": "textarea", "name": "Reason", "enabled": true}},
+				{"id": "test-disabled-field-guid", "type": "form_fields", "attributes": {"kind": "custom", "input_kind": "text", "name": "Old", "enabled": false}},
+				{"id": "test-users-field-guid", "type": "form_fields", "attributes": {"kind": "custom", "input_kind": "users", "name": "Approvers", "enabled": true}}
+			],
+			"links": {"next": null}
+		}`,
+		"GET /v1/form_fields/test-system-field-guid/options": `{
+			"data": [
+				{"id": "test-database-option-guid", "type": "form_field_options", "attributes": {"value": "Database"}},
+				{"id": "test-kubernetes-option-guid", "type": "form_field_options", "attributes": {"value": "Kubernetes"}}
+			],
+			"links": {"next": null}
+		}`,
+		"POST /v1/incidents/test-incident-guid/form_field_selections": `{"data": {"id": "test-selection-guid", "type": "incident_form_field_selections"}}`,
+		"POST /v1/incidents/test-incident-guid/action_items": `{"data": {"id": "test-action-item-guid", "type": "incident_action_items", "attributes": {
+			"summary": "Revoke access", "status": "open", "incident_id": "test-incident-guid"
+		}}}`,
+	})
+	fake.handle("GET /v1/incident_types", func(writer http.ResponseWriter, request *http.Request) {
+		state.mu.Lock()
+		var incidentTypes []client.IncidentType
+		require.NoError(t, json.Unmarshal([]byte(state.incidentTypes), &incidentTypes))
+		state.mu.Unlock()
+		resp := client.IncidentTypesResponse{}
+		resp.Data, resp.Links.Next = paginate(t, request, incidentTypes)
+		writeJSON(t, writer, http.StatusOK, resp)
+	})
+	fake.handle("POST /v1/incidents", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, writer, http.StatusCreated, incident())
+	})
+	fake.handle("GET /v1/incidents/test-incident-guid", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(t, writer, http.StatusOK, incident())
+	})
+	return state
+}
+
+func TestConnector_ListTicketSchemas(t *testing.T) {
+	fake := newFakeRootly(t)
+	serveTickets(t, fake)
+	ctx := context.Background()
+	c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL))
+	require.NoError(t, err)
+
+	schemas, nextPage, _, err := c.ListTicketSchemas(ctx, &pagination.Token{})
+	require.NoError(t, err)
+	require.Empty(t, nextPage)
+	require.Len(t, schemas, 1)
+	schema := schemas[0]
+	require.Equal(t, "test-incident-type-guid", schema.Id)
+	require.Equal(t, "Access request", schema.DisplayName)
+	require.Len(t, schema.Types, 2)
+
+	// the built-in, disabled and unsupported form fields are left out
+	var customFieldIDs []string
+	for id := range schema.CustomFields {
+		customFieldIDs = append(customFieldIDs, id)
+	}
+	require.ElementsMatch(t, []string{
+		"severity",
+		"incident_id",
+		"form_field:test-system-field-guid",
+		"form_field:test-reason-field-guid",
+	}, customFieldIDs)
+	system := schema.CustomFields["form_field:test-system-field-guid"].GetPickObjectValue()
+	require.Len(t, system.GetAllowedValues(), 2)
+	require.Equal(t, "Database", system.GetAllowedValues()[0].DisplayName)
+
+	got, _, err := c.GetTicketSchema(ctx, "test-incident-type-guid")
+	require.NoError(t, err)
+	require.Equal(t, schema, got)
+	_, _, err = c.GetTicketSchema(ctx, "unknown")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	t.Run("no incident types", func(t *testing.T) {
+		fake := newFakeRootly(t)
+		serveTickets(t, fake).setIncidentTypes(`[]`)
+		c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL))
+		require.NoError(t, err)
+		schemas, _, _, err := c.ListTicketSchemas(ctx, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, schemas, 1)
+		require.Equal(t, defaultTicketSchemaID, schemas[0].Id)
+	})
+
+	t.Run("several pages", func(t *testing.T) {
+		fake := newFakeRootly(t)
+		serveTickets(t, fake).setIncidentTypes(`[
+			{"id": "test-incident-type-guid", "type": "incident_types", "attributes": {"name": "Access request"}},
+			{"id": "test-other-incident-type-guid", "type": "incident_types", "attributes": {"name": "Outage"}}
+		]`)
+		c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL), WithPageSize(1))
+		require.NoError(t, err)
+		first, nextPage, _, err := c.ListTicketSchemas(ctx, &pagination.Token{})
+		require.NoError(t, err)
+		require.NotEmpty(t, nextPage)
+		second, nextPage, _, err := c.ListTicketSchemas(ctx, &pagination.Token{Token: nextPage})
+		require.NoError(t, err)
+		require.Empty(t, nextPage)
+		require.Equal(t, "test-other-incident-type-guid", second[0].Id)
+		// the custom fields computed for the first page are reused
+		require.Same(t, first[0].CustomFields["severity"], second[0].CustomFields["severity"])
+
+		// while a new listing computes them again
+		again, _, _, err := c.ListTicketSchemas(ctx, &pagination.Token{})
+		require.NoError(t, err)
+		require.NotSame(t, first[0].CustomFields["severity"], again[0].CustomFields["severity"])
+	})
+}
+
+func TestConnector_CreateTicket(t *testing.T) {
+	fake := newFakeRootly(t)
+	serveTickets(t, fake)
+	incidents := func() []client.IncidentRequest {
+		return decodeBodies[client.IncidentRequest](t, fake, http.MethodPost, "/v1/incidents")
+	}
+	ctx := context.Background()
+	c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL))
+	require.NoError(t, err)
+	schema, _, err := c.GetTicketSchema(ctx, "test-incident-type-guid")
+	require.NoError(t, err)
+
+	t.Run("incident", func(t *testing.T) {
+		severity, err := sdkTicket.CustomFieldForSchemaField("severity", schema, &v2.TicketCustomFieldObjectValue{Id: "test-sev1-guid"})
+		require.NoError(t, err)
+		system, err := sdkTicket.CustomFieldForSchemaField("form_field:test-system-field-guid", schema,
+			&v2.TicketCustomFieldObjectValue{Id: "test-kubernetes-option-guid"})
+		require.NoError(t, err)
+		reason, err := sdkTicket.CustomFieldForSchemaField("form_field:test-reason-field-guid", schema, "On-call")
+		require.NoError(t, err)
+
+		ticket, _, err := c.CreateTicket(ctx, &v2.Ticket{
+			DisplayName: "Access to production",
+			Description: "Jane needs access to production",
+			CustomFields: map[string]*v2.TicketCustomField{
+				"severity":                          severity,
+				"form_field:test-system-field-guid": system,
+				"form_field:test-reason-field-guid": reason,
+			},
+		}, schema)
+		require.NoError(t, err)
+		require.Equal(t, "incident:test-incident-guid", ticket.Id)
+		require.Equal(t, "started", ticket.Status.Id)
+		require.Equal(t, "https://rootly.com/account/incidents/1", ticket.Url)
+
+		require.Len(t, incidents(), 1)
+		require.Equal(t, client.IncidentRequestAttributes{
+			Title:           "Access to production",
+			Summary:         "Jane needs access to production",
+			SeverityID:      "test-sev1-guid",
+			IncidentTypeIDs: []string{"test-incident-type-guid"},
+		}, incidents()[0].Data.Attributes)
+
+		var selections []client.FormFieldSelectionRequestAttributes
+		for _, selection := range decodeBodies[client.FormFieldSelectionRequest](t, fake, http.MethodPost,
+			"/v1/incidents/test-incident-guid/form_field_selections") {
+			selections = append(selections, selection.Data.Attributes)
+		}
+		require.ElementsMatch(t, []client.FormFieldSelectionRequestAttributes{
+			{FormFieldID: "test-system-field-guid", SelectedOptionIDs: []string{"test-kubernetes-option-guid"}},
+			{FormFieldID: "test-reason-field-guid", Value: "On-call"},
+		}, selections)
+	})
+
+	t.Run("action item", func(t *testing.T) {
+		incidentID, err := sdkTicket.CustomFieldForSchemaField("incident_id", schema, "test-incident-guid")
+		require.NoError(t, err)
+		ticket, _, err := c.CreateTicket(ctx, &v2.Ticket{
+			DisplayName:  "Revoke access",
+			Type:         &v2.TicketType{Id: actionItemTicketType},
+			CustomFields: map[string]*v2.TicketCustomField{"incident_id": incidentID},
+		}, schema)
+		require.NoError(t, err)
+		require.Equal(t, "action_item:test-action-item-guid", ticket.Id)
+		require.Equal(t, "open", ticket.Status.Id)
+		actionItems := decodeBodies[client.ActionItemRequest](t, fake, http.MethodPost,
+			"/v1/incidents/test-incident-guid/action_items")
+		require.Len(t, actionItems, 1)
+		require.Equal(t, client.ActionItemRequestAttributes{
+			Summary: "Revoke access",
+			Kind:    "task",
+		}, actionItems[0].Data.Attributes)
+
+		_, _, err = c.CreateTicket(ctx, &v2.Ticket{
+			DisplayName: "Revoke access",
+			Type:        &v2.TicketType{Id: actionItemTicketType},
+		}, schema)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		_, _, err := c.CreateTicket(ctx, &v2.Ticket{
+			DisplayName: "Access to production",
+			Status:      &v2.TicketStatus{Id: "open"},
+		}, schema)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Len(t, incidents(), 1)
+	})
+}
+
+func TestConnector_GetTicket(t *testing.T) {
+	fake := newFakeRootly(t)
+	state := serveTickets(t, fake)
+	ctx := context.Background()
+	c, err := New(ctx, "test-api-key", WithBaseURL(fake.URL))
+	require.NoError(t, err)
+
+	ticket, _, err := c.GetTicket(ctx, "incident:test-incident-guid")
+	require.NoError(t, err)
+	require.Equal(t, "started", ticket.Status.Id)
+	require.Nil(t, ticket.CompletedAt)
+
+	// the status is polled from Rootly rather than the cache of the HTTP client
+	state.setIncidentStatus("resolved")
+	ticket, _, err = c.GetTicket(ctx, "incident:test-incident-guid")
+	require.NoError(t, err)
+	require.Equal(t, &v2.TicketStatus{Id: "resolved", DisplayName: "Resolved"}, ticket.Status)
+	require.NotNil(t, ticket.CompletedAt)
+
+	_, _, err = c.GetTicket(ctx, "test-incident-guid")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := c.BulkGetTickets(ctx, &v2.TicketsServiceBulkGetTicketsRequest{
+		TicketRequests: []*v2.TicketsServiceGetTicketRequest{
+			{Id: "incident:test-incident-guid"},
+			{Id: "incident:unknown"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Tickets, 2)
+	require.Empty(t, resp.Tickets[0].Error)
+	require.NotEmpty(t, resp.Tickets[1].Error)
+}
@@ -13,9 +13,19 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// invitationPendingStatus details the status of the users who haven't accepted their invitation to Rootly yet.
+	invitationPendingStatus = "invitation_pending"
+	// neverSignedInStatus details the status of the users who can use Rootly but never signed in, e.g. because
+	// they were provisioned without an invitation.
+	neverSignedInStatus = "never_signed_in"
+)
+
 type userBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.Client
+	// syncContacts enables fetching the contact methods and notification rules of each user into their profile.
+	syncContacts bool
 }
 
 func (o *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -50,16 +60,26 @@ func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
+	// fetch the contact methods of the users of the page, which takes a few requests per user
+	contacts := make([]*client.UserContacts, len(users))
+	if o.syncContacts {
+		userIDs := make([]string, len(users))
+		for i, user := range users {
+			userIDs[i] = user.ID
+		}
+		allContacts, err := o.client.ListAllUserContactsConcurrently(ctx, userIDs)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		for i := range allContacts {
+			contacts[i] = &allContacts[i]
+		}
+	}
+
 	// create user resources using the SDK
 	var resources []*v2.Resource
-	for _, user := range users {
-		userResource, err := sdkResource.NewUserResource(
-			getBestName(user.Attributes),
-			o.resourceType,
-			user.ID,
-			getUserTraitOptions(user),
-			sdkResource.WithParentResourceID(parentResourceID),
-		)
+	for i, user := range users {
+		userResource, err := newUserResource(user, contacts[i], parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -73,20 +93,73 @@ func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
-	return resources, nextPage, nil, nil
+	return resources, nextPage, withRateLimit(nil, o.client), nil
+}
+
+// Get returns a single user, e.g. for a targeted sync after the user changed, or nil if it no longer exists.
+func (o *userBuilder) Get(ctx context.Context, resourceID *v2.ResourceId, parentResourceID *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	user, err := o.client.GetUser(ctx, resourceID.Resource)
+	if client.IsNotFound(err) {
+		return nil, withRateLimit(nil, o.client), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var contacts *client.UserContacts
+	if o.syncContacts {
+		contacts, err = o.client.GetUserContacts(ctx, user.ID)
+		if err != nil && !client.IsNotFound(err) && !client.IsForbidden(err) {
+			return nil, nil, err
+		}
+	}
+
+	userResource, err := newUserResource(*user, contacts, parentResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return userResource, withRateLimit(nil, o.client), nil
+}
+
+// newUserResource creates a user resource using the SDK. The contacts of the user are nil when they weren't fetched.
+func newUserResource(user client.User, contacts *client.UserContacts, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return sdkResource.NewUserResource(
+		getBestName(user.Attributes),
+		userResourceType,
+		user.ID,
+		getUserTraitOptions(user, contacts),
+		sdkResource.WithParentResourceID(parentResourceID),
+	)
 }
 
 // getUserTraitOptions returns a list of UserTraitOption's based on the available fields for a Rootly user.
-func getUserTraitOptions(user client.User) []sdkResource.UserTraitOption {
+func getUserTraitOptions(user client.User, contacts *client.UserContacts) []sdkResource.UserTraitOption {
 	traitOpts := []sdkResource.UserTraitOption{
 		sdkResource.WithEmail(user.Attributes.Email, true),
-		// always set status to enabled, since Rootly doesn't allow for disabled user status
-		sdkResource.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
-		sdkResource.WithUserProfile(getUserProfile(user)),
+		sdkResource.WithUserProfile(getUserProfile(user, contacts)),
+	}
+	// Rootly doesn't allow for disabled user status, but invited users can't use Rootly until they accept the invitation,
+	// and users who never signed in hold a seat without using it
+	switch {
+	case isInvitationPending(user):
+		traitOpts = append(traitOpts, sdkResource.WithDetailedStatus(v2.UserTrait_Status_STATUS_DISABLED, invitationPendingStatus))
+	case !hasSignedIn(user):
+		traitOpts = append(traitOpts, sdkResource.WithDetailedStatus(v2.UserTrait_Status_STATUS_ENABLED, neverSignedInStatus))
+	default:
+		traitOpts = append(traitOpts, sdkResource.WithStatus(v2.UserTrait_Status_STATUS_ENABLED))
 	}
 	if t, err := time.Parse(time.RFC3339, user.Attributes.CreatedAt); err == nil {
 		traitOpts = append(traitOpts, sdkResource.WithCreatedAt(t))
 	}
+	for _, signInAt := range []string{user.Attributes.CurrentSignInAt, user.Attributes.LastSignInAt} {
+		if t, err := time.Parse(time.RFC3339, signInAt); err == nil {
+			traitOpts = append(traitOpts, sdkResource.WithLastLogin(t))
+			break
+		}
+	}
+	if user.Attributes.AvatarURL != "" {
+		traitOpts = append(traitOpts, sdkResource.WithUserIcon(assetRef(userResourceType.Id, user.ID)))
+	}
 	if user.Attributes.FullName != "" {
 		first, last := sdkResource.SplitFullName(user.Attributes.FullName)
 		traitOpts = append(traitOpts, sdkResource.WithStructuredName(&v2.UserTrait_StructuredName{
@@ -97,8 +170,19 @@ func getUserTraitOptions(user client.User) []sdkResource.UserTraitOption {
 	return traitOpts
 }
 
-// getUserProfile builds a map of profile fields from the available user fields.
-func getUserProfile(user client.User) map[string]interface{} {
+// isInvitationPending reports whether the user was invited to Rootly but hasn't accepted the invitation yet.
+func isInvitationPending(user client.User) bool {
+	return user.Attributes.InvitationSentAt != "" && user.Attributes.InvitationAcceptedAt == ""
+}
+
+// hasSignedIn reports whether the user ever signed in to Rootly.
+func hasSignedIn(user client.User) bool {
+	return user.Attributes.CurrentSignInAt != "" || user.Attributes.LastSignInAt != ""
+}
+
+// getUserProfile builds a map of profile fields from the available user fields, and from the contacts of the user
+// when they were fetched.
+func getUserProfile(user client.User, contacts *client.UserContacts) map[string]interface{} {
 	// required Rootly fields
 	profile := map[string]interface{}{
 		"user_id":    user.ID,
@@ -112,6 +196,20 @@ func getUserProfile(user client.User) map[string]interface{} {
 	if user.Attributes.Phone != "" {
 		profile["phone"] = user.Attributes.Phone
 	}
+	if user.Attributes.InvitationSentAt != "" {
+		profile["invitation_sent_at"] = user.Attributes.InvitationSentAt
+	}
+	if user.Attributes.InvitationAcceptedAt != "" {
+		profile["invitation_accepted_at"] = user.Attributes.InvitationAcceptedAt
+	}
+
+	// contact methods, left out rather than reported as zero when they couldn't be read
+	if contacts != nil && contacts.Complete {
+		profile["verified_phone_numbers"] = contacts.VerifiedPhoneNumbers
+		profile["verified_email_addresses"] = contacts.VerifiedEmailAddresses
+		profile["notification_rules"] = contacts.NotificationRules
+		profile["pageable"] = contacts.Pageable()
+	}
 	return profile
 }
 
@@ -137,9 +235,10 @@ func (o *userBuilder) Grants(ctx context.Context, _ *v2.Resource, _ *pagination.
 	return nil, "", nil, nil
 }
 
-func newUserBuilder(client *client.Client) *userBuilder {
+func newUserBuilder(client *client.Client, syncContacts bool) *userBuilder {
 	return &userBuilder{
 		client:       client,
 		resourceType: userResourceType,
+		syncContacts: syncContacts,
 	}
 }
@@ -2,8 +2,11 @@ package connector
 
 import (
 	"testing"
+	"time"
 
 	"github.com/conductorone/baton-rootly/pkg/connector/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	sdkResource "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/stretchr/testify/require"
 )
 
@@ -57,7 +60,8 @@ func Test_getBestName(t *testing.T) {
 
 func Test_getUserProfile(t *testing.T) {
 	type args struct {
-		user client.User
+		user     client.User
+		contacts *client.UserContacts
 	}
 	tests := []struct {
 		name string
@@ -104,6 +108,94 @@ func Test_getUserProfile(t *testing.T) {
 				"updated_at": "2023-01-02T00:00:00Z",
 			},
 		},
+		{
+			name: "Invitation pending",
+			args: args{
+				user: client.User{
+					ID: "126",
+					Attributes: client.UserAttributes{
+						UpdatedAt:        "2023-01-02T00:00:00Z",
+						Email:            "sam.testalot@example.com", // not captured in profile
+						InvitationSentAt: "2023-01-01T00:00:00Z",
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"user_id":            "126",
+				"updated_at":         "2023-01-02T00:00:00Z",
+				"invitation_sent_at": "2023-01-01T00:00:00Z",
+			},
+		},
+		{
+			name: "Pageable",
+			args: args{
+				user: client.User{
+					ID: "127",
+					Attributes: client.UserAttributes{
+						UpdatedAt: "2023-01-02T00:00:00Z",
+						Email:     "sam.testalot@example.com", // not captured in profile
+					},
+				},
+				contacts: &client.UserContacts{
+					UserID:                 "127",
+					VerifiedPhoneNumbers:   1,
+					VerifiedEmailAddresses: 2,
+					NotificationRules:      3,
+					Complete:               true,
+				},
+			},
+			want: map[string]interface{}{
+				"user_id":                  "127",
+				"updated_at":               "2023-01-02T00:00:00Z",
+				"verified_phone_numbers":   1,
+				"verified_email_addresses": 2,
+				"notification_rules":       3,
+				"pageable":                 true,
+			},
+		},
+		{
+			name: "Not pageable without a verified phone number",
+			args: args{
+				user: client.User{
+					ID: "128",
+					Attributes: client.UserAttributes{
+						UpdatedAt: "2023-01-02T00:00:00Z",
+						Email:     "sam.testalot@example.com", // not captured in profile
+					},
+				},
+				contacts: &client.UserContacts{
+					UserID:                 "128",
+					VerifiedEmailAddresses: 1,
+					NotificationRules:      1,
+					Complete:               true,
+				},
+			},
+			want: map[string]interface{}{
+				"user_id":                  "128",
+				"updated_at":               "2023-01-02T00:00:00Z",
+				"verified_phone_numbers":   0,
+				"verified_email_addresses": 1,
+				"notification_rules":       1,
+				"pageable":                 false,
+			},
+		},
+		{
+			name: "Contacts not readable",
+			args: args{
+				user: client.User{
+					ID: "129",
+					Attributes: client.UserAttributes{
+						UpdatedAt: "2023-01-02T00:00:00Z",
+						Email:     "sam.testalot@example.com", // not captured in profile
+					},
+				},
+				contacts: &client.UserContacts{UserID: "129"},
+			},
+			want: map[string]interface{}{
+				"user_id":    "129",
+				"updated_at": "2023-01-02T00:00:00Z",
+			},
+		},
 		{
 			name: "Optional fields partially populated",
 			args: args{
@@ -125,8 +217,77 @@ func Test_getUserProfile(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := getUserProfile(tc.args.user)
+			got := getUserProfile(tc.args.user, tc.args.contacts)
 			require.Equal(t, tc.want, got)
 		})
 	}
 }
+
+func Test_getUserTraitOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		attributes    client.UserAttributes
+		wantStatus    v2.UserTrait_Status_Status
+		wantDetails   string
+		wantLastLogin time.Time
+	}{
+		{
+			name: "signed in",
+			attributes: client.UserAttributes{
+				InvitationSentAt:     "2023-01-01T00:00:00Z",
+				InvitationAcceptedAt: "2023-01-02T00:00:00Z",
+				CurrentSignInAt:      "2023-03-01T10:00:00.123-07:00",
+				LastSignInAt:         "2023-02-01T10:00:00Z",
+			},
+			wantStatus:    v2.UserTrait_Status_STATUS_ENABLED,
+			wantLastLogin: time.Date(2023, 3, 1, 17, 0, 0, 123000000, time.UTC),
+		},
+		{
+			name: "signed in once",
+			attributes: client.UserAttributes{
+				LastSignInAt: "2023-02-01T10:00:00Z",
+			},
+			wantStatus:    v2.UserTrait_Status_STATUS_ENABLED,
+			wantLastLogin: time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invitation pending",
+			attributes: client.UserAttributes{
+				InvitationSentAt: "2023-01-01T00:00:00Z",
+			},
+			wantStatus:  v2.UserTrait_Status_STATUS_DISABLED,
+			wantDetails: invitationPendingStatus,
+		},
+		{
+			name: "invitation accepted but never signed in",
+			attributes: client.UserAttributes{
+				InvitationSentAt:     "2023-01-01T00:00:00Z",
+				InvitationAcceptedAt: "2023-01-02T00:00:00Z",
+			},
+			wantStatus:  v2.UserTrait_Status_STATUS_ENABLED,
+			wantDetails: neverSignedInStatus,
+		},
+		{
+			name:        "no invitation or sign in state",
+			attributes:  client.UserAttributes{},
+			wantStatus:  v2.UserTrait_Status_STATUS_ENABLED,
+			wantDetails: neverSignedInStatus,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.attributes.Email = "sam.testalot@example.com"
+			resource, err := newUserResource(client.User{ID: "123", Attributes: tc.attributes}, nil, nil)
+			require.NoError(t, err)
+			trait, err := sdkResource.GetUserTrait(resource)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, trait.GetStatus().GetStatus())
+			require.Equal(t, tc.wantDetails, trait.GetStatus().GetDetails())
+			if tc.wantLastLogin.IsZero() {
+				require.Nil(t, trait.GetLastLogin())
+			} else {
+				require.Equal(t, tc.wantLastLogin, trait.GetLastLogin().AsTime())
+			}
+		})
+	}
+}